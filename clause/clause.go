@@ -21,9 +21,19 @@ const (
 	LIMIT
 	OFFSET
 	ORDERBY
+	RETURNING
+	JOIN
+	GROUPBY
+	HAVING
+	// DISTINCT is SELECT DISTINCT, set in place of SELECT
+	DISTINCT
+	// WITH holds the common table expressions, rendered before the statement
+	WITH
+	// ONCONFLICT handles the conflicts of INSERT, rendered by the dialect
+	ONCONFLICT
 )
 
-var Operator = []Type{INSERT, VALUES, UPDATE, DELETE, COUNT, SELECT, TABLE, WHERE, LIMIT, OFFSET, ORDERBY}
+var Operator = []Type{WITH, INSERT, VALUES, ONCONFLICT, RETURNING, UPDATE, DELETE, COUNT, DISTINCT, SELECT, TABLE, JOIN, WHERE, GROUPBY, HAVING, ORDERBY, LIMIT, OFFSET}
 
 func (c *Clause) Set(name Type, vars ...interface{}) {
 	if c.sql == nil {
@@ -48,6 +58,27 @@ func (c *Clause) Build(orders ...Type) (string, []interface{}) {
 	return strings.Join(sqls, " "), vars
 }
 
+// Unset removes the clause name
+func (c *Clause) Unset(name Type) {
+	delete(c.sql, name)
+	delete(c.sqlVars, name)
+}
+
+// Clone returns a copy of c, setting the copy leaves c unchanged
+func (c *Clause) Clone() Clause {
+	clone := Clause{}
+	if c.sql == nil {
+		return clone
+	}
+	clone.sql = make(map[Type]string, len(c.sql))
+	clone.sqlVars = make(map[Type][]interface{}, len(c.sqlVars))
+	for name, sql := range c.sql {
+		clone.sql[name] = sql
+		clone.sqlVars[name] = append([]interface{}(nil), c.sqlVars[name]...)
+	}
+	return clone
+}
+
 func (c *Clause) Get(name Type) (string, []interface{}) {
 	return c.sql[name], c.sqlVars[name]
 }
@@ -4,17 +4,21 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestSelect(t *testing.T) {
 	var clause Clause
 	clause.Set(LIMIT, 3)
-	clause.Set(SELECT, "test_user", []string{"*"})
+	clause.Set(SELECT, []string{"*"})
+	clause.Set(TABLE, "test_user")
 	clause.Set(WHERE, "SqlName = ?", "Tom")
 	clause.Set(ORDERBY, "name Asc")
 
-	sql, vars := clause.Build(SELECT, WHERE, ORDERBY, LIMIT)
-	t.Log(sql, vars)
+	sql, vars := clause.Build(SELECT, TABLE, WHERE, ORDERBY, LIMIT)
+	assert.Equal(t, "SELECT * FROM test_user WHERE SqlName = ? ORDER BY name Asc LIMIT ?", sql)
+	assert.Equal(t, []interface{}{"Tom", 3}, vars)
 }
 
 func TestCheckIn(t *testing.T) {
@@ -24,3 +28,45 @@ func TestCheckIn(t *testing.T) {
 		fmt.Println("fail")
 	}
 }
+
+func TestInsert(t *testing.T) {
+	var clause Clause
+	clause.Set(INSERT, "user_test", []string{"name", "age"})
+	clause.Set(VALUES, []interface{}{"Tom", 18}, []interface{}{"Sam", 20})
+
+	sql, vars := clause.Build(INSERT, VALUES)
+	assert.Equal(t, "INSERT INTO user_test(name,age) VALUES (?,?),(?,?)", sql)
+	assert.Equal(t, []interface{}{"Tom", 18, "Sam", 20}, vars)
+
+	clause.Set(RETURNING, []string{"id", "name", "age"})
+	sql, _ = clause.Build(INSERT, VALUES, RETURNING)
+	assert.Equal(t, "INSERT INTO user_test(name,age) VALUES (?,?),(?,?) RETURNING id,name,age", sql)
+}
+
+func TestExpression(t *testing.T) {
+	sql, vars := Eq{"name": "Tom", "age": 18, "deleted_at": nil, "id": []int{1, 2}}.Build()
+	assert.Equal(t, "age = ? AND deleted_at IS NULL AND id IN (?) AND name = ?", sql)
+	assert.Equal(t, []interface{}{18, []int{1, 2}, "Tom"}, vars)
+
+	sql, vars = And(Eq{"a": 1}, Or(Eq{"b": 2}, Expr{SQL: "c > ? AND c < ?", Vars: []interface{}{3, 4}}), Not(In("d", []int{5}))).Build()
+	assert.Equal(t, "a = ? AND (b = ? OR (c > ? AND c < ?)) AND NOT (d IN (?))", sql)
+	assert.Equal(t, []interface{}{1, 2, 3, 4, []int{5}}, vars)
+
+	sql, _ = And(And(Eq{"a": 1}, Eq{"b": 2}), nil, Eq{"c": 3}).Build()
+	assert.Equal(t, "a = ? AND b = ? AND c = ?", sql)
+
+	sql, vars = Or(Between("age", 18, 30), Like("name", EscapeLike("10%_!")+"%")).Build()
+	assert.Equal(t, "(age BETWEEN ? AND ?) OR name LIKE ? ESCAPE '!'", sql)
+	assert.Equal(t, []interface{}{18, 30, "10!%!_!!%"}, vars)
+
+	sql, vars = InTuple([]string{"team_id", "user_id"}, [][]interface{}{{1, 2}, {1, 3}}).Build()
+	assert.Equal(t, "(team_id,user_id) IN ((?,?),(?,?))", sql)
+	assert.Equal(t, []interface{}{1, 2, 1, 3}, vars)
+
+	sql, vars = InTuple([]string{"id"}, [][]interface{}{{1}, {2}}).Build()
+	assert.Equal(t, "id IN (?)", sql)
+	assert.Equal(t, []interface{}{[]interface{}{1, 2}}, vars)
+
+	sql, _ = InTuple([]string{"team_id", "user_id"}, nil).Build()
+	assert.Equal(t, "1 = 0", sql)
+}
@@ -0,0 +1,226 @@
+package clause
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Expression is a condition of a WHERE clause, rendered with ? placeholders,
+// a slice argument is expanded into one placeholder per element when the
+// statement is bound
+type Expression interface {
+	Build() (string, []interface{})
+}
+
+// Expr is a condition written in SQL, such as Expr{SQL: "age > ?", Vars: []interface{}{18}}
+type Expr struct {
+	SQL  string
+	Vars []interface{}
+}
+
+func (e Expr) Build() (string, []interface{}) {
+	return e.SQL, e.Vars
+}
+
+// Eq matches the columns equal to the values, keys are rendered in order,
+// a nil value renders IS NULL and a slice renders IN
+type Eq map[string]interface{}
+
+func (eq Eq) Build() (string, []interface{}) {
+	columns := make([]string, 0, len(eq))
+	for column := range eq {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	var conditions []string
+	var vars []interface{}
+	for _, column := range columns {
+		value := eq[column]
+		switch {
+		case isNil(value):
+			conditions = append(conditions, column+" IS NULL")
+		case isList(value):
+			conditions = append(conditions, column+" IN (?)")
+			vars = append(vars, value)
+		default:
+			conditions = append(conditions, column+" = ?")
+			vars = append(vars, value)
+		}
+	}
+	return strings.Join(conditions, " AND "), vars
+}
+
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// isList reports whether value is bound as a list, []byte and driver.Valuer
+// types are single values
+func isList(value interface{}) bool {
+	if _, ok := value.(driver.Valuer); ok {
+		return false
+	}
+	typ := reflect.TypeOf(value)
+	return (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) && typ.Elem().Kind() != reflect.Uint8
+}
+
+type andExpr []Expression
+
+// And matches when all of exprs match
+func And(exprs ...Expression) Expression {
+	return andExpr(flatten(exprs, func(expr Expression) ([]Expression, bool) {
+		and, ok := expr.(andExpr)
+		return and, ok
+	}))
+}
+
+func (and andExpr) Build() (string, []interface{}) {
+	return join([]Expression(and), " AND ")
+}
+
+type orExpr []Expression
+
+// Or matches when any of exprs matches
+func Or(exprs ...Expression) Expression {
+	return orExpr(flatten(exprs, func(expr Expression) ([]Expression, bool) {
+		or, ok := expr.(orExpr)
+		return or, ok
+	}))
+}
+
+func (or orExpr) Build() (string, []interface{}) {
+	return join([]Expression(or), " OR ")
+}
+
+type notExpr struct {
+	expr Expression
+}
+
+// Not matches when expr does not match
+func Not(expr Expression) Expression {
+	return notExpr{expr: expr}
+}
+
+func (not notExpr) Build() (string, []interface{}) {
+	sql, vars := not.expr.Build()
+	if sql == "" {
+		return "", nil
+	}
+	return "NOT (" + sql + ")", vars
+}
+
+// In matches the column in the list values, an empty list matches nothing,
+// values may be a subquery, an Expression rendered in parentheses
+func In(column string, values interface{}) Expression {
+	if _, ok := values.(Expression); ok {
+		return Expr{SQL: column + " IN ?", Vars: []interface{}{values}}
+	}
+	return Expr{SQL: column + " IN (?)", Vars: []interface{}{values}}
+}
+
+// InTuple matches the columns in the list of tuples, one value per column,
+// the lookup of a composite key, an empty list matches nothing
+//
+//	clause.InTuple([]string{"team_id", "user_id"}, [][]interface{}{{1, 2}, {1, 3}})
+func InTuple(columns []string, tuples [][]interface{}) Expression {
+	if len(columns) == 1 {
+		values := make([]interface{}, 0, len(tuples))
+		for _, tuple := range tuples {
+			values = append(values, tuple[0])
+		}
+		return In(columns[0], values)
+	}
+	if len(tuples) == 0 {
+		return Expr{SQL: "1 = 0"}
+	}
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",") + ")"
+	rows := make([]string, 0, len(tuples))
+	var vars []interface{}
+	for _, tuple := range tuples {
+		rows = append(rows, placeholders)
+		vars = append(vars, tuple...)
+	}
+	return Expr{SQL: "(" + strings.Join(columns, ",") + ") IN (" + strings.Join(rows, ",") + ")", Vars: vars}
+}
+
+// Between matches the column from from to to, both included
+func Between(column string, from, to interface{}) Expression {
+	return Expr{SQL: column + " BETWEEN ? AND ?", Vars: []interface{}{from, to}}
+}
+
+// likeEscape is the escape character of Like, the same on every dialect,
+// unlike a backslash which mysql also reads as a string escape
+const likeEscape = "!"
+
+// Like matches the column against pattern, % and _ of the pattern are
+// wildcards, escape literal text with EscapeLike:
+//
+//	clause.Like("name", clause.EscapeLike(input)+"%")
+func Like(column, pattern string) Expression {
+	return Expr{SQL: column + " LIKE ? ESCAPE '" + likeEscape + "'", Vars: []interface{}{pattern}}
+}
+
+var likeReplacer = strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_")
+
+// EscapeLike escapes the wildcards of s so Like matches it literally
+func EscapeLike(s string) string {
+	return likeReplacer.Replace(s)
+}
+
+// flatten inlines the nested expressions of the same kind and drops the empty ones
+func flatten(exprs []Expression, nested func(Expression) ([]Expression, bool)) []Expression {
+	var flat []Expression
+	for _, expr := range exprs {
+		if expr == nil {
+			continue
+		}
+		if children, ok := nested(expr); ok {
+			flat = append(flat, flatten(children, nested)...)
+			continue
+		}
+		flat = append(flat, expr)
+	}
+	return flat
+}
+
+var conjunction = regexp.MustCompile(`(?i)\b(and|or)\b`)
+
+// compound reports whether expr joins several conditions, it is wrapped in
+// parentheses when joined with other conditions
+func compound(expr Expression) bool {
+	switch e := expr.(type) {
+	case andExpr:
+		return len(e) > 1
+	case orExpr:
+		return len(e) > 1
+	case Eq:
+		return len(e) > 1
+	case Expr:
+		return conjunction.MatchString(e.SQL)
+	}
+	return false
+}
+
+func join(exprs []Expression, sep string) (string, []interface{}) {
+	var conditions []string
+	var vars []interface{}
+	for _, expr := range exprs {
+		sql, exprVars := expr.Build()
+		if sql == "" {
+			continue
+		}
+		if len(exprs) > 1 && compound(expr) {
+			sql = "(" + sql + ")"
+		}
+		conditions = append(conditions, sql)
+		vars = append(vars, exprVars...)
+	}
+	return strings.Join(conditions, sep), vars
+}
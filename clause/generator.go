@@ -2,6 +2,7 @@ package clause
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -13,6 +14,7 @@ func init() {
 	generators = make(map[Type]generator)
 	generators[INSERT] = _insert
 	generators[VALUES] = _values
+	generators[RETURNING] = _returning
 	generators[SELECT] = _select
 	generators[TABLE] = _table
 	generators[LIMIT] = _limit
@@ -22,6 +24,12 @@ func init() {
 	generators[UPDATE] = _update
 	generators[DELETE] = _delete
 	generators[COUNT] = _count
+	generators[JOIN] = _join
+	generators[GROUPBY] = _groupby
+	generators[HAVING] = _having
+	generators[DISTINCT] = _distinct
+	generators[WITH] = _with
+	generators[ONCONFLICT] = _onconflict
 }
 
 func genBindVars(num int) string {
@@ -44,28 +52,43 @@ func _values(values ...interface{}) (string, []interface{}) {
 	var sqlStr strings.Builder
 	var vars []interface{}
 	sqlStr.WriteString("VALUES ")
-	for i, value := range values[1:] {
+	for i, value := range values {
 		v := value.([]interface{})
 		if bindStr == "" {
 			bindStr = genBindVars(len(v))
 		}
 		sqlStr.WriteString(fmt.Sprintf("(%v)", bindStr))
-		if i+2 != len(values) {
+		if i+1 != len(values) {
 			sqlStr.WriteString(",")
 		}
 		vars = append(vars, v...)
 	}
-	sqlStr.WriteString(fmt.Sprintf(" RETURNING %v", strings.Join(values[0].([]string), ",")))
 	return sqlStr.String(), vars
 }
 
+func _onconflict(values ...interface{}) (string, []interface{}) {
+	// ON CONFLICT ... or ON DUPLICATE KEY UPDATE ...
+	return fmt.Sprintf("%s", values[0]), []interface{}{}
+}
+
+func _returning(values ...interface{}) (string, []interface{}) {
+	// RETURNING $fields
+	return fmt.Sprintf("RETURNING %v", strings.Join(values[0].([]string), ",")), []interface{}{}
+}
+
 func _select(values ...interface{}) (string, []interface{}) {
 	// SELECT $fields FROM $tableName
-	return fmt.Sprintf("SELECT %v FROM", strings.Join(values[0].([]string), ",")), []interface{}{}
+	return fmt.Sprintf("SELECT %v FROM", strings.Join(values[0].([]string), ",")), values[1:]
+}
+
+func _distinct(values ...interface{}) (string, []interface{}) {
+	// SELECT DISTINCT $fields FROM $tableName
+	return fmt.Sprintf("SELECT DISTINCT %v FROM", strings.Join(values[0].([]string), ",")), values[1:]
 }
 
 func _table(values ...interface{}) (string, []interface{}) {
-	return fmt.Sprintf("%s ", values[0]), []interface{}{}
+	// $tableName, the arguments follow a derived table
+	return fmt.Sprintf("%s", values[0]), values[1:]
 }
 
 func _limit(values ...interface{}) (string, []interface{}) {
@@ -76,10 +99,24 @@ func _limit(values ...interface{}) (string, []interface{}) {
 func _where(values ...interface{}) (string, []interface{}) {
 	// WHERE $desc
 	desc, vars := values[0], values[1:]
-	if strings.Contains(desc.(string), " WHERE ") {
+	if strings.HasPrefix(desc.(string), "WHERE ") {
 		return fmt.Sprintf("%s", desc), vars
 	}
-	return fmt.Sprintf(" WHERE %s", desc), vars
+	return fmt.Sprintf("WHERE %s", desc), vars
+}
+
+func _with(values ...interface{}) (string, []interface{}) {
+	// WITH $desc, the queries are arguments of desc
+	return fmt.Sprintf("WITH %s", values[0]), values[1:]
+}
+
+func _groupby(values ...interface{}) (string, []interface{}) {
+	return fmt.Sprintf("GROUP BY %s", values[0]), []interface{}{}
+}
+
+func _having(values ...interface{}) (string, []interface{}) {
+	// HAVING $desc
+	return fmt.Sprintf("HAVING %s", values[0]), values[1:]
 }
 
 func _orderby(values ...interface{}) (string, []interface{}) {
@@ -94,13 +131,19 @@ func _update(values ...interface{}) (string, []interface{}) {
 	// UPDATE $tableName set $fields
 	tableName := values[0]
 	m := values[1].(map[string]interface{})
+	// sorted so the same update always renders the same statement
 	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sets []string
 	var vars []interface{}
-	for k, v := range m {
-		keys = append(keys, k+"=?")
-		vars = append(vars, v)
+	for _, k := range keys {
+		sets = append(sets, k+"=?")
+		vars = append(vars, m[k])
 	}
-	return fmt.Sprintf("UPDATE %v SET %v", tableName, strings.Join(keys, ",")), vars
+	return fmt.Sprintf("UPDATE %v SET %v", tableName, strings.Join(sets, ",")), vars
 }
 
 func _delete(values ...interface{}) (string, []interface{}) {
@@ -110,3 +153,15 @@ func _delete(values ...interface{}) (string, []interface{}) {
 func _count(values ...interface{}) (string, []interface{}) {
 	return _select([]string{"count(*)"})
 }
+
+func _join(values ...interface{}) (string, []interface{}) {
+	// $join1 $join2 ...
+	var joins []string
+	var vars []interface{}
+	for _, value := range values {
+		sql, joinVars := value.(Expression).Build()
+		joins = append(joins, sql)
+		vars = append(vars, joinVars...)
+	}
+	return strings.Join(joins, " "), vars
+}
@@ -1,12 +1,129 @@
 package dialect
 
-import "reflect"
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
 
 var dialectsMap = map[string]Dialect{}
 
+// BindVarStyle is the placeholder syntax a driver accepts
+type BindVarStyle int
+
+const (
+	// Question renders placeholders as ?
+	Question BindVarStyle = iota
+	// Dollar renders placeholders as $1, $2, ...
+	Dollar
+	// Colon renders placeholders as :1, :2, ...
+	Colon
+	// AtP renders placeholders as @p1, @p2, ...
+	AtP
+)
+
+// Placeholder renders the n-th (1-based) placeholder of a statement
+func (b BindVarStyle) Placeholder(n int) string {
+	switch b {
+	case Dollar:
+		return "$" + strconv.Itoa(n)
+	case Colon:
+		return ":" + strconv.Itoa(n)
+	case AtP:
+		return "@p" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// Syntax is the lexical syntax of a dialect departing from standard SQL,
+// the statements are split into tokens accordingly
+type Syntax struct {
+	// BackslashEscapes makes a backslash escape the next character of every
+	// quoted string, not only of E'...' strings
+	BackslashEscapes bool
+	// HashComments makes # start a comment running to the end of the line
+	HashComments bool
+}
+
 type Dialect interface {
 	DataTypeOf(typ reflect.Value) string
+	// ColumnTypeOf returns the column type of typ with a size (0 for the
+	// default) applied to strings, auto incremented when autoIncrement is set
+	ColumnTypeOf(typ reflect.Value, size int, autoIncrement bool) string
 	TableExistSQL(tableName string) (string, []interface{})
+	// BindVarStyle reports the placeholder syntax of the driver
+	BindVarStyle() BindVarStyle
+	// Syntax reports how string literals and comments are written
+	Syntax() Syntax
+	// SupportReturning reports whether INSERT ... RETURNING is available,
+	// when it is not, primary keys are populated through LastInsertId
+	SupportReturning() bool
+	// MaxBindVars is the most arguments a statement may bind, batch inserts
+	// are split into statements below it
+	MaxBindVars() int
+	// UpsertSQL renders the clause following the VALUES of an insert to
+	// handle the conflicts of upsert
+	UpsertSQL(upsert *Upsert) string
+	// CreateIndexSQL renders the statement creating index, an error is
+	// returned for features the database lacks such as partial indexes
+	CreateIndexSQL(index *Index) (string, error)
+	DropIndexSQL(tableName, indexName string) string
+	IndexExistSQL(tableName, indexName string) (string, []interface{})
+	// IndexesSQL lists the indexes of a table, but the ones backing a primary
+	// key or unique constraint, as rows of (name, create statement)
+	IndexesSQL(tableName string) (string, []interface{})
+	// TriggersSQL lists the triggers of a table as rows of (name, create statement)
+	TriggersSQL(tableName string) (string, []interface{})
+	// ColumnsSQL lists the columns of a table as rows of (name, type, not
+	// null, default), the default is an expression or NULL
+	ColumnsSQL(tableName string) (string, []interface{})
+	// CompareType compares the current type of a column, as listed by
+	// ColumnsSQL, with the type rendered for the model
+	CompareType(current, target string) TypeChange
+	// AlterColumnSQL changes a column to definition (name, type and
+	// constraints) of type dataType, ok is false when the database cannot
+	// alter the column in place
+	AlterColumnSQL(tableName, columnName, definition, dataType string) (sql string, ok bool)
+	// DropColumnSQL drops a column, ok is false when the database cannot
+	// drop the column in place
+	DropColumnSQL(tableName, columnName string) (sql string, ok bool)
+	// ForeignKeys returns the statements suspending the foreign keys while a
+	// table is rebuilt, nil when the dialect alters and drops columns in place
+	ForeignKeys() *ForeignKeys
+	// Lock returns the statements of the named lock serializing migrations
+	Lock(name string) *Lock
+}
+
+// Index describes an index of a table
+type Index struct {
+	Table   string
+	Name    string
+	Unique  bool
+	Columns []string
+	// Expression is indexed instead of Columns when set, such as lower(email)
+	Expression string
+	// Where restricts a partial index to the matching rows
+	Where string
+}
+
+// createIndexSQL renders CREATE [UNIQUE] INDEX name ON table (columns) [WHERE ...]
+func createIndexSQL(index *Index) string {
+	var sql strings.Builder
+	sql.WriteString("CREATE ")
+	if index.Unique {
+		sql.WriteString("UNIQUE ")
+	}
+	sql.WriteString(fmt.Sprintf("INDEX %s ON %s ", index.Name, index.Table))
+	if index.Expression != "" {
+		sql.WriteString(fmt.Sprintf("((%s))", index.Expression))
+	} else {
+		sql.WriteString(fmt.Sprintf("(%s)", strings.Join(index.Columns, ",")))
+	}
+	if index.Where != "" {
+		sql.WriteString(" WHERE " + index.Where)
+	}
+	return sql.String()
 }
 
 func RegisterDialect(name string, dialect Dialect) {
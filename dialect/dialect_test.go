@@ -0,0 +1,58 @@
+package dialect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaceholder(t *testing.T) {
+	assert.Equal(t, "?", Question.Placeholder(2))
+	assert.Equal(t, "$2", Dollar.Placeholder(2))
+	assert.Equal(t, ":2", Colon.Placeholder(2))
+	assert.Equal(t, "@p2", AtP.Placeholder(2))
+}
+
+func TestCreateIndexSQL(t *testing.T) {
+	index := &Index{Table: "account", Name: "idx_tenant_email", Unique: true, Columns: []string{"tenant_id", "email"}}
+	partial := &Index{Table: "account", Name: "idx_active", Columns: []string{"status"}, Where: "status = 'a'"}
+	expression := &Index{Table: "account", Name: "idx_lower_email", Expression: "lower(email)"}
+
+	for _, name := range []string{"postgres", "sqlite3", "mysql"} {
+		dial, _ := GetDialect(name)
+		sql, err := dial.CreateIndexSQL(index)
+		assert.Nil(t, err)
+		assert.Equal(t, "CREATE UNIQUE INDEX idx_tenant_email ON account (tenant_id,email)", sql)
+
+		sql, err = dial.CreateIndexSQL(expression)
+		assert.Nil(t, err)
+		assert.Equal(t, "CREATE INDEX idx_lower_email ON account ((lower(email)))", sql)
+
+		sql, err = dial.CreateIndexSQL(partial)
+		if name == "mysql" {
+			assert.NotNil(t, err)
+			assert.Equal(t, "DROP INDEX idx_active ON account", dial.DropIndexSQL("account", "idx_active"))
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, "CREATE INDEX idx_active ON account (status) WHERE status = 'a'", sql)
+		assert.Equal(t, "DROP INDEX IF EXISTS idx_active", dial.DropIndexSQL("account", "idx_active"))
+	}
+}
+
+func TestUpsertSQL(t *testing.T) {
+	pg, _ := GetDialect("postgres")
+	sqlite, _ := GetDialect("sqlite3")
+	mysql, _ := GetDialect("mysql")
+	inserted := []string{"id", "name", "age"}
+
+	nothing := &Upsert{Columns: []string{"id"}, DoNothing: true, Inserted: inserted}
+	assert.Equal(t, "ON CONFLICT (id) DO NOTHING", pg.UpsertSQL(nothing))
+	assert.Equal(t, "ON CONFLICT DO NOTHING", sqlite.UpsertSQL(&Upsert{DoNothing: true, Inserted: inserted}))
+	assert.Equal(t, "ON DUPLICATE KEY UPDATE id = id", mysql.UpsertSQL(nothing))
+
+	update := &Upsert{Columns: []string{"id"}, Updates: []string{"name", "age"}, Inserted: inserted}
+	assert.Equal(t, "ON CONFLICT (id) DO UPDATE SET name = excluded.name,age = excluded.age", pg.UpsertSQL(update))
+	assert.Equal(t, "ON CONFLICT (id) DO UPDATE SET name = excluded.name,age = excluded.age", sqlite.UpsertSQL(update))
+	assert.Equal(t, "ON DUPLICATE KEY UPDATE name = VALUES(name),age = VALUES(age)", mysql.UpsertSQL(update))
+}
@@ -0,0 +1,61 @@
+package dialect
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// LockTableName is the table holding the locks of the dialects without
+// advisory locks
+const LockTableName = "sorm_locks"
+
+// LockMode is how a dialect reports that a lock was taken
+type LockMode int
+
+const (
+	// AdvisoryLock is held by the connection that took it, AcquireSQL returns
+	// one row holding whether the lock was taken
+	AdvisoryLock LockMode = iota
+	// TableLock is a row of the lock table, the lock is taken when AcquireSQL
+	// inserts a row
+	TableLock
+)
+
+// Lock holds the statements taking and releasing a named database lock,
+// AcquireSQL never waits, Args are the arguments of every statement
+type Lock struct {
+	Mode LockMode
+	// CreateSQL creates the lock table, empty for advisory locks
+	CreateSQL  string
+	AcquireSQL string
+	ReleaseSQL string
+	// RefreshSQL sets the time a table lock was last held to now, empty for
+	// advisory locks, released with their connection
+	RefreshSQL string
+	// StaleSQL deletes a table lock not refreshed for the number of seconds
+	// bound after Args, left by a process that died holding it
+	StaleSQL string
+	Args     []interface{}
+}
+
+// lockKey hashes name into the int64 key of advisory locks
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// tableLock locks name with a row of the lock table, insertIgnore is the
+// statement inserting a row unless the key already exists, staleBefore the
+// expression of the time ? seconds ago
+func tableLock(name, insertIgnore, staleBefore string) *Lock {
+	return &Lock{
+		Mode:       TableLock,
+		CreateSQL:  fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (name varchar(255) PRIMARY KEY, locked_at timestamp NOT NULL)", LockTableName),
+		AcquireSQL: fmt.Sprintf("%s %s (name, locked_at) VALUES (?, CURRENT_TIMESTAMP)", insertIgnore, LockTableName),
+		ReleaseSQL: fmt.Sprintf("DELETE FROM %s WHERE name = ?", LockTableName),
+		RefreshSQL: fmt.Sprintf("UPDATE %s SET locked_at = CURRENT_TIMESTAMP WHERE name = ?", LockTableName),
+		StaleSQL:   fmt.Sprintf("DELETE FROM %s WHERE name = ? AND locked_at < %s", LockTableName, staleBefore),
+		Args:       []interface{}{name},
+	}
+}
@@ -0,0 +1,86 @@
+package dialect
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TypeChange classifies moving a column from its current type to another
+type TypeChange int
+
+const (
+	// TypeSame means both types are the same column type
+	TypeSame TypeChange = iota
+	// TypeWiden means the new type holds every value of the current one
+	TypeWiden
+	// TypeNarrow means the change may lose data or is not a conversion at all
+	TypeNarrow
+)
+
+// ForeignKeys holds the statements suspending the foreign keys of a
+// connection while a table is rebuilt, dropping the table would otherwise
+// delete the rows referencing it ON DELETE CASCADE or fail
+type ForeignKeys struct {
+	// EnabledSQL returns whether the connection enforces foreign keys
+	EnabledSQL string
+	// DisableSQL and EnableSQL have no effect inside a transaction
+	DisableSQL string
+	EnableSQL  string
+	// CheckSQL lists the rows violating a foreign key, one row each
+	CheckSQL string
+}
+
+var sizedType = regexp.MustCompile(`^([a-z ]+?)\s*\((\d+)\)$`)
+
+// typeFamily ranks the types of one family, a type widens to the types of
+// the same family with a higher rank
+type typeFamily map[string]int
+
+// compareType compares normalized types, sized types such as varchar(64)
+// widen to larger sizes and to the unsized types ranked above the sized name
+func compareType(current, target string, families ...typeFamily) TypeChange {
+	if current == target {
+		return TypeSame
+	}
+	currentName, currentSize := splitSize(current)
+	targetName, targetSize := splitSize(target)
+	for _, family := range families {
+		from, ok := family[currentName]
+		if !ok {
+			continue
+		}
+		to, ok := family[targetName]
+		if !ok {
+			return TypeNarrow
+		}
+		switch {
+		case from < to:
+			return TypeWiden
+		case from == to && currentName == targetName && currentSize > 0 && (targetSize == 0 || targetSize > currentSize):
+			return TypeWiden
+		}
+		return TypeNarrow
+	}
+	return TypeNarrow
+}
+
+func splitSize(dataType string) (string, int) {
+	if m := sizedType.FindStringSubmatch(dataType); m != nil {
+		size, _ := strconv.Atoi(m[2])
+		return m[1], size
+	}
+	return dataType, 0
+}
+
+func normalizeType(dataType string, aliases map[string]string) string {
+	dataType = strings.Join(strings.Fields(strings.ToLower(dataType)), " ")
+	name, size := splitSize(dataType)
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+	if size > 0 {
+		return name + "(" + strconv.Itoa(size) + ")"
+	}
+	return name
+}
@@ -0,0 +1,31 @@
+package dialect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareType(t *testing.T) {
+	pg, _ := GetDialect("postgres")
+	assert.Equal(t, TypeSame, pg.CompareType("integer", "serial"))
+	assert.Equal(t, TypeSame, pg.CompareType("character varying(64)", "varchar(64)"))
+	assert.Equal(t, TypeWiden, pg.CompareType("integer", "bigint"))
+	assert.Equal(t, TypeWiden, pg.CompareType("character varying(64)", "varchar(255)"))
+	assert.Equal(t, TypeWiden, pg.CompareType("character varying(64)", "text"))
+	assert.Equal(t, TypeNarrow, pg.CompareType("bigint", "integer"))
+	assert.Equal(t, TypeNarrow, pg.CompareType("text", "integer"))
+
+	my, _ := GetDialect("mysql")
+	assert.Equal(t, TypeSame, my.CompareType("int(11)", "int"))
+	assert.Equal(t, TypeSame, my.CompareType("bigint", "bigint AUTO_INCREMENT"))
+	assert.Equal(t, TypeWiden, my.CompareType("int", "bigint"))
+	assert.Equal(t, TypeWiden, my.CompareType("varchar(64)", "longtext"))
+	assert.Equal(t, TypeNarrow, my.CompareType("tinyint(1)", "tinyint"))
+	assert.Equal(t, TypeNarrow, my.CompareType("varchar(255)", "varchar(64)"))
+
+	lite, _ := GetDialect("sqlite3")
+	assert.Equal(t, TypeSame, lite.CompareType("int", "integer"))
+	assert.Equal(t, TypeSame, lite.CompareType("varchar(64)", "text"))
+	assert.Equal(t, TypeNarrow, lite.CompareType("text", "integer"))
+}
@@ -0,0 +1,195 @@
+package dialect
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type mysql struct{}
+
+var _ Dialect = (*mysql)(nil)
+
+func init() {
+	RegisterDialect("mysql", &mysql{})
+}
+
+func (m *mysql) DataTypeOf(typ reflect.Value) string {
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "tinyint(1)"
+	case reflect.Int8, reflect.Uint8:
+		return "tinyint"
+	case reflect.Int16, reflect.Uint16:
+		return "smallint"
+	case reflect.Int32, reflect.Uint32:
+		return "int"
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return "bigint"
+	case reflect.Float32:
+		return "float"
+	case reflect.Float64:
+		return "double"
+	case reflect.String:
+		return "varchar(255)"
+	case reflect.Array, reflect.Slice:
+		return "longblob"
+	case reflect.Struct: // 关于时间的处理方法
+		if _, ok := typ.Interface().(time.Time); ok {
+			return "datetime"
+		}
+	}
+	panic(fmt.Sprintf("invalid sql type %s (%s)", typ.Type().Name(), typ.Kind()))
+}
+
+func (m *mysql) TableExistSQL(tableName string) (string, []interface{}) {
+	args := []interface{}{tableName}
+	return "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() and table_name = ?", args
+}
+
+func (m *mysql) BindVarStyle() BindVarStyle {
+	return Question
+}
+
+// Syntax of mysql escapes with a backslash in every string, unless the sql
+// mode sets NO_BACKSLASH_ESCAPES, and starts comments with #
+func (m *mysql) Syntax() Syntax {
+	return Syntax{BackslashEscapes: true, HashComments: true}
+}
+
+func (m *mysql) SupportReturning() bool {
+	return false
+}
+
+// MaxBindVars is the limit of prepared statements, which count placeholders in 16 bits
+func (m *mysql) MaxBindVars() int {
+	return 65535
+}
+
+// UpsertSQL renders ON DUPLICATE KEY UPDATE, which handles the conflicts of
+// every unique key, DoNothing sets a column to itself
+func (m *mysql) UpsertSQL(upsert *Upsert) string {
+	updates := upsert.Updates
+	if upsert.DoNothing || len(updates) == 0 {
+		var column string
+		switch {
+		case len(upsert.Columns) > 0:
+			column = upsert.Columns[0]
+		case len(upsert.Inserted) > 0:
+			column = upsert.Inserted[0]
+		}
+		return fmt.Sprintf("ON DUPLICATE KEY UPDATE %s = %s", column, column)
+	}
+	sets := make([]string, 0, len(updates))
+	for _, column := range updates {
+		sets = append(sets, fmt.Sprintf("%s = VALUES(%s)", column, column))
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ",")
+}
+
+func (m *mysql) ColumnTypeOf(typ reflect.Value, size int, autoIncrement bool) string {
+	switch typ.Kind() {
+	case reflect.String:
+		if size > 16383 { // the longest utf8mb4 varchar
+			return "longtext"
+		}
+		if size > 0 {
+			return fmt.Sprintf("varchar(%d)", size)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if autoIncrement {
+			return m.DataTypeOf(typ) + " AUTO_INCREMENT"
+		}
+	}
+	return m.DataTypeOf(typ)
+}
+
+func (m *mysql) CreateIndexSQL(index *Index) (string, error) {
+	if index.Where != "" {
+		return "", fmt.Errorf("mysql does not support partial index %s", index.Name)
+	}
+	return createIndexSQL(index), nil
+}
+
+func (m *mysql) DropIndexSQL(tableName, indexName string) string {
+	return fmt.Sprintf("DROP INDEX %s ON %s", indexName, tableName)
+}
+
+func (m *mysql) IndexExistSQL(tableName, indexName string) (string, []interface{}) {
+	args := []interface{}{tableName, indexName}
+	return "SELECT index_name FROM information_schema.statistics WHERE table_schema = DATABASE() and table_name = ? and index_name = ? LIMIT 1", args
+}
+
+// ColumnsSQL quotes the literal defaults, information_schema lists them bare
+func (m *mysql) ColumnsSQL(tableName string) (string, []interface{}) {
+	args := []interface{}{tableName}
+	return "SELECT column_name, column_type, is_nullable = 'NO', " +
+		"CASE WHEN column_default IS NULL OR extra LIKE '%DEFAULT_GENERATED%' THEN column_default ELSE QUOTE(column_default) END " +
+		"FROM information_schema.columns WHERE table_schema = DATABASE() and table_name = ? ORDER BY ordinal_position", args
+}
+
+var (
+	mysqlAliases  = map[string]string{"integer": "int", "bool": "tinyint(1)", "boolean": "tinyint(1)", "real": "double"}
+	mysqlIntegers = typeFamily{"tinyint": 1, "smallint": 2, "mediumint": 3, "int": 4, "bigint": 5}
+	mysqlFloats   = typeFamily{"float": 1, "double": 2}
+	mysqlStrings  = typeFamily{"varchar": 1, "text": 2, "mediumtext": 3, "longtext": 4}
+	mysqlBlobs    = typeFamily{"blob": 1, "mediumblob": 2, "longblob": 3}
+	displayWidth  = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+)
+
+func (m *mysql) normalizeType(dataType string) string {
+	dataType = strings.ToLower(strings.TrimSpace(dataType))
+	dataType = strings.TrimSpace(strings.TrimSuffix(dataType, "auto_increment"))
+	if dataType != "tinyint(1)" {
+		// display widths such as int(11) are not part of the type
+		dataType = displayWidth.ReplaceAllString(dataType, "$1")
+	}
+	return normalizeType(dataType, mysqlAliases)
+}
+
+func (m *mysql) CompareType(current, target string) TypeChange {
+	current, target = m.normalizeType(current), m.normalizeType(target)
+	if current == "tinyint(1)" || target == "tinyint(1)" {
+		if current == target {
+			return TypeSame
+		}
+		return TypeNarrow
+	}
+	return compareType(current, target, mysqlIntegers, mysqlFloats, mysqlStrings, mysqlBlobs)
+}
+
+func (m *mysql) AlterColumnSQL(tableName, columnName, definition, dataType string) (string, bool) {
+	return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s", tableName, definition), true
+}
+
+func (m *mysql) DropColumnSQL(tableName, columnName string) (string, bool) {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", tableName, columnName), true
+}
+
+func (m *mysql) Lock(name string) *Lock {
+	return tableLock(name, "INSERT IGNORE INTO", "CURRENT_TIMESTAMP - INTERVAL ? SECOND")
+}
+
+func (m *mysql) ForeignKeys() *ForeignKeys {
+	return nil
+}
+
+// TriggersSQL rebuilds the statements from information_schema like IndexesSQL
+func (m *mysql) TriggersSQL(tableName string) (string, []interface{}) {
+	args := []interface{}{tableName}
+	return "SELECT trigger_name, CONCAT('CREATE TRIGGER ', trigger_name, ' ', action_timing, ' ', event_manipulation, ' ON ', event_object_table, " +
+		"' FOR EACH ROW ', action_statement) FROM information_schema.triggers WHERE trigger_schema = DATABASE() and event_object_table = ?", args
+}
+
+// IndexesSQL rebuilds the statements from information_schema, mysql keeps no
+// create statement of its indexes
+func (m *mysql) IndexesSQL(tableName string) (string, []interface{}) {
+	args := []interface{}{tableName}
+	return "SELECT index_name, CONCAT(IF(non_unique = 0, 'CREATE UNIQUE INDEX ', 'CREATE INDEX '), index_name, ' ON ', table_name, " +
+		"' (', GROUP_CONCAT(IFNULL(column_name, CONCAT('(', expression, ')')) ORDER BY seq_in_index), ')') " +
+		"FROM information_schema.statistics WHERE table_schema = DATABASE() and table_name = ? and index_name <> 'PRIMARY' " +
+		"GROUP BY index_name, non_unique, table_name", args
+}
@@ -0,0 +1,47 @@
+package dialect
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMysqlDataTypeOf(t *testing.T) {
+	dial, ok := GetDialect("mysql")
+	assert.True(t, ok)
+
+	cases := []struct {
+		value interface{}
+		want  string
+	}{
+		{"", "varchar(255)"},
+		{true, "tinyint(1)"},
+		{int32(0), "int"},
+		{0, "bigint"},
+		{int64(0), "bigint"},
+		{1.0, "double"},
+		{[]byte{}, "longblob"},
+		{time.Time{}, "datetime"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, dial.DataTypeOf(reflect.ValueOf(c.value)))
+	}
+}
+
+func TestMysqlTableExistSQL(t *testing.T) {
+	dial, _ := GetDialect("mysql")
+	sql, args := dial.TableExistSQL("user_test")
+	assert.Equal(t, "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() and table_name = ?", sql)
+	assert.Equal(t, []interface{}{"user_test"}, args)
+	assert.Equal(t, Question, dial.BindVarStyle())
+	assert.False(t, dial.SupportReturning())
+}
+
+func TestMysqlColumnTypeOf(t *testing.T) {
+	dial, _ := GetDialect("mysql")
+	assert.Equal(t, "varchar(64)", dial.ColumnTypeOf(reflect.ValueOf(""), 64, false))
+	assert.Equal(t, "longtext", dial.ColumnTypeOf(reflect.ValueOf(""), 100000, false))
+	assert.Equal(t, "bigint AUTO_INCREMENT", dial.ColumnTypeOf(reflect.ValueOf(int64(0)), 0, true))
+}
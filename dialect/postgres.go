@@ -38,5 +38,137 @@ func (p *postgres) DataTypeOf(typ reflect.Value) string {
 
 func (p postgres) TableExistSQL(tableName string) (string, []interface{}) {
 	args := []interface{}{tableName}
-	return "SELECT tablename FROM pg_tables WHERE schemaname='public' and tablename=$1", args
+	return "SELECT tablename FROM pg_tables WHERE schemaname='public' and tablename=?", args
+}
+
+func (p *postgres) BindVarStyle() BindVarStyle {
+	return Dollar
+}
+
+func (p *postgres) Syntax() Syntax {
+	return Syntax{}
+}
+
+func (p *postgres) SupportReturning() bool {
+	return true
+}
+
+// MaxBindVars is the limit of the wire protocol, which counts parameters in 16 bits
+func (p *postgres) MaxBindVars() int {
+	return 65535
+}
+
+func (p *postgres) UpsertSQL(upsert *Upsert) string {
+	return onConflictSQL(upsert)
+}
+
+func (p *postgres) ColumnTypeOf(typ reflect.Value, size int, autoIncrement bool) string {
+	switch typ.Kind() {
+	case reflect.String:
+		if size > 0 {
+			return fmt.Sprintf("varchar(%d)", size)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if autoIncrement {
+			if p.DataTypeOf(typ) == "bigint" {
+				return "bigserial"
+			}
+			return "serial"
+		}
+	}
+	return p.DataTypeOf(typ)
+}
+
+func (p *postgres) CreateIndexSQL(index *Index) (string, error) {
+	return createIndexSQL(index), nil
+}
+
+func (p *postgres) DropIndexSQL(tableName, indexName string) string {
+	return fmt.Sprintf("DROP INDEX IF EXISTS %s", indexName)
+}
+
+func (p *postgres) IndexExistSQL(tableName, indexName string) (string, []interface{}) {
+	args := []interface{}{tableName, indexName}
+	return "SELECT indexname FROM pg_indexes WHERE schemaname='public' and tablename=? and indexname=?", args
+}
+
+func (p *postgres) ColumnsSQL(tableName string) (string, []interface{}) {
+	args := []interface{}{tableName}
+	return "SELECT a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull, pg_get_expr(d.adbin, d.adrelid) FROM pg_attribute a " +
+		"JOIN pg_class c ON a.attrelid = c.oid JOIN pg_namespace n ON c.relnamespace = n.oid " +
+		"LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum " +
+		"WHERE n.nspname='public' and c.relname=? and a.attnum > 0 and NOT a.attisdropped ORDER BY a.attnum", args
+}
+
+var (
+	postgresAliases = map[string]string{
+		"character varying":           "varchar",
+		"character":                   "char",
+		"integer":                     "int",
+		"int4":                        "int",
+		"serial":                      "int",
+		"int8":                        "bigint",
+		"bigserial":                   "bigint",
+		"int2":                        "smallint",
+		"smallserial":                 "smallint",
+		"boolean":                     "bool",
+		"float":                       "double precision",
+		"float8":                      "double precision",
+		"float4":                      "real",
+		"timestamp without time zone": "timestamp",
+		"timestamp with time zone":    "timestamptz",
+	}
+	postgresIntegers = typeFamily{"smallint": 1, "int": 2, "bigint": 3}
+	postgresFloats   = typeFamily{"real": 1, "double precision": 2}
+	postgresStrings  = typeFamily{"varchar": 1, "text": 2}
+)
+
+func (p *postgres) CompareType(current, target string) TypeChange {
+	current, target = normalizeType(current, postgresAliases), normalizeType(target, postgresAliases)
+	if target == "varchar" && current == "text" {
+		return TypeSame
+	}
+	return compareType(current, target, postgresIntegers, postgresFloats, postgresStrings)
+}
+
+func (p *postgres) AlterColumnSQL(tableName, columnName, definition, dataType string) (string, bool) {
+	switch dataType {
+	case "serial":
+		dataType = "int"
+	case "bigserial":
+		dataType = "bigint"
+	}
+	return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s", tableName, columnName, dataType), true
+}
+
+func (p *postgres) DropColumnSQL(tableName, columnName string) (string, bool) {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", tableName, columnName), true
+}
+
+// Lock uses a session advisory lock, released when the connection closes
+func (p *postgres) Lock(name string) *Lock {
+	return &Lock{
+		Mode:       AdvisoryLock,
+		AcquireSQL: "SELECT pg_try_advisory_lock(?)",
+		ReleaseSQL: "SELECT pg_advisory_unlock(?)",
+		Args:       []interface{}{lockKey(name)},
+	}
+}
+
+func (p *postgres) ForeignKeys() *ForeignKeys {
+	return nil
+}
+
+func (p *postgres) TriggersSQL(tableName string) (string, []interface{}) {
+	args := []interface{}{tableName}
+	return "SELECT t.tgname, pg_get_triggerdef(t.oid) FROM pg_trigger t " +
+		"JOIN pg_class c ON t.tgrelid = c.oid JOIN pg_namespace n ON c.relnamespace = n.oid " +
+		"WHERE n.nspname='public' and c.relname=? and NOT t.tgisinternal", args
+}
+
+func (p *postgres) IndexesSQL(tableName string) (string, []interface{}) {
+	args := []interface{}{tableName}
+	return "SELECT indexname, indexdef FROM pg_indexes i WHERE schemaname='public' and tablename=? " +
+		"and NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conname = i.indexname)", args
 }
@@ -3,6 +3,7 @@ package dialect
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 )
 
@@ -43,3 +44,110 @@ func (s *sqlite3) TableExistSQL(tableName string) (string, []interface{}) {
 	args := []interface{}{tableName}
 	return "SELECT name FROM sqlite_master WHERE type='table' and name = ?", args
 }
+
+func (s *sqlite3) BindVarStyle() BindVarStyle {
+	return Question
+}
+
+func (s *sqlite3) Syntax() Syntax {
+	return Syntax{}
+}
+
+func (s *sqlite3) SupportReturning() bool {
+	return true
+}
+
+// MaxBindVars is SQLITE_MAX_VARIABLE_NUMBER before sqlite 3.32, which raised it to 32766
+func (s *sqlite3) MaxBindVars() int {
+	return 999
+}
+
+func (s *sqlite3) UpsertSQL(upsert *Upsert) string {
+	return onConflictSQL(upsert)
+}
+
+// ColumnTypeOf ignores size, an integer primary key is the auto incremented rowid
+func (s *sqlite3) ColumnTypeOf(typ reflect.Value, size int, autoIncrement bool) string {
+	if autoIncrement {
+		return "integer"
+	}
+	return s.DataTypeOf(typ)
+}
+
+func (s *sqlite3) CreateIndexSQL(index *Index) (string, error) {
+	return createIndexSQL(index), nil
+}
+
+func (s *sqlite3) DropIndexSQL(tableName, indexName string) string {
+	return fmt.Sprintf("DROP INDEX IF EXISTS %s", indexName)
+}
+
+func (s *sqlite3) IndexExistSQL(tableName, indexName string) (string, []interface{}) {
+	args := []interface{}{tableName, indexName}
+	return "SELECT name FROM sqlite_master WHERE type='index' and tbl_name = ? and name = ?", args
+}
+
+func (s *sqlite3) ColumnsSQL(tableName string) (string, []interface{}) {
+	args := []interface{}{tableName}
+	return `SELECT name, type, "notnull", dflt_value FROM pragma_table_info(?)`, args
+}
+
+// CompareType compares the type affinities, sqlite stores any value in any
+// column so only a change of affinity is a change of type
+func (s *sqlite3) CompareType(current, target string) TypeChange {
+	if sqliteAffinity(current) == sqliteAffinity(target) {
+		return TypeSame
+	}
+	return TypeNarrow
+}
+
+// sqliteAffinity follows https://www.sqlite.org/datatype3.html#determination_of_column_affinity
+func sqliteAffinity(dataType string) string {
+	dataType = strings.ToUpper(dataType)
+	switch {
+	case strings.Contains(dataType, "INT"):
+		return "INTEGER"
+	case strings.Contains(dataType, "CHAR"), strings.Contains(dataType, "CLOB"), strings.Contains(dataType, "TEXT"):
+		return "TEXT"
+	case dataType == "", strings.Contains(dataType, "BLOB"):
+		return "BLOB"
+	case strings.Contains(dataType, "REAL"), strings.Contains(dataType, "FLOA"), strings.Contains(dataType, "DOUB"):
+		return "REAL"
+	}
+	return "NUMERIC"
+}
+
+// AlterColumnSQL is not supported, sqlite cannot change a column in place
+func (s *sqlite3) AlterColumnSQL(tableName, columnName, definition, dataType string) (string, bool) {
+	return "", false
+}
+
+// DropColumnSQL is not supported, sqlite before 3.35 cannot drop a column in place
+func (s *sqlite3) DropColumnSQL(tableName, columnName string) (string, bool) {
+	return "", false
+}
+
+func (s *sqlite3) Lock(name string) *Lock {
+	return tableLock(name, "INSERT OR IGNORE INTO", "datetime('now', '-' || ? || ' seconds')")
+}
+
+// ForeignKeys follows https://www.sqlite.org/lang_altertable.html#otheralter
+func (s *sqlite3) ForeignKeys() *ForeignKeys {
+	return &ForeignKeys{
+		EnabledSQL: "PRAGMA foreign_keys",
+		DisableSQL: "PRAGMA foreign_keys = OFF",
+		EnableSQL:  "PRAGMA foreign_keys = ON",
+		CheckSQL:   "PRAGMA foreign_key_check",
+	}
+}
+
+func (s *sqlite3) TriggersSQL(tableName string) (string, []interface{}) {
+	args := []interface{}{tableName}
+	return "SELECT name, sql FROM sqlite_master WHERE type='trigger' and tbl_name = ?", args
+}
+
+// IndexesSQL skips the autoindexes of constraints, they have no sql
+func (s *sqlite3) IndexesSQL(tableName string) (string, []interface{}) {
+	args := []interface{}{tableName}
+	return "SELECT name, sql FROM sqlite_master WHERE type='index' and tbl_name = ? and sql IS NOT NULL", args
+}
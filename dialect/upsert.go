@@ -0,0 +1,38 @@
+package dialect
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Upsert is the conflict handling of an insert
+type Upsert struct {
+	// Columns are the unique columns a conflict is detected on, empty for any
+	// unique constraint, the target is required to update on postgres and sqlite
+	Columns []string
+	// DoNothing keeps the existing rows as they are
+	DoNothing bool
+	// Updates are the columns set to the inserted values on conflict
+	Updates []string
+	// Inserted are the columns of the insert
+	Inserted []string
+}
+
+// onConflictSQL renders the ON CONFLICT clause of postgres and sqlite
+func onConflictSQL(upsert *Upsert) string {
+	var sql strings.Builder
+	sql.WriteString("ON CONFLICT")
+	if len(upsert.Columns) > 0 {
+		sql.WriteString(fmt.Sprintf(" (%s)", strings.Join(upsert.Columns, ",")))
+	}
+	if upsert.DoNothing || len(upsert.Updates) == 0 {
+		sql.WriteString(" DO NOTHING")
+		return sql.String()
+	}
+	sets := make([]string, 0, len(upsert.Updates))
+	for _, column := range upsert.Updates {
+		sets = append(sets, fmt.Sprintf("%s = excluded.%s", column, column))
+	}
+	sql.WriteString(" DO UPDATE SET " + strings.Join(sets, ","))
+	return sql.String()
+}
@@ -1,13 +1,25 @@
-package main
+package sorm
 
 import (
-	"errors"
 	"github.com/catbugdemo/sorm/log"
 )
 
 var (
 	// ErrRecordNotFound
 	ErrRecordNotFound = log.ErrRecordNotFound
-	//
-	ErrValuesNotPointer = errors.New("values not pointer")
+	// ErrQueryCanceled is returned when the context bound to a session is done
+	ErrQueryCanceled = log.ErrQueryCanceled
+	// ErrLockTimeout is returned when a database lock is not released in time
+	ErrLockTimeout = log.ErrLockTimeout
+	// ErrDryRun is returned by the statements of a session in dry run mode
+	ErrDryRun = log.ErrDryRun
+	// ErrMissingPrimaryKey is returned when the model has no primary key, or
+	// when its primary key is blank where a row must be identified
+	ErrMissingPrimaryKey = log.ErrMissingPrimaryKey
+	// ErrInvalidModel is returned when a model cannot be parsed, such as a
+	// field with a malformed sorm tag
+	ErrInvalidModel = log.ErrInvalidModel
+	// ErrValuesNotPointer is returned when the destination of a query is not
+	// a pointer of the expected kind, such as Pluck given a slice
+	ErrValuesNotPointer = log.ErrValuesNotPointer
 )
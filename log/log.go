@@ -8,7 +8,15 @@ import (
 	"sync"
 )
 
-var ErrRecordNotFound = errors.New("record not found")
+var (
+	ErrRecordNotFound    = errors.New("record not found")
+	ErrQueryCanceled     = errors.New("query canceled")
+	ErrLockTimeout       = errors.New("lock timeout")
+	ErrDryRun            = errors.New("dry run")
+	ErrMissingPrimaryKey = errors.New("missing primary key")
+	ErrInvalidModel      = errors.New("invalid model")
+	ErrValuesNotPointer  = errors.New("values not pointer")
+)
 
 var (
 	errLog  = log.New(os.Stdout, "\033[31m[error]\033[0m ", log.LstdFlags)
@@ -0,0 +1,350 @@
+// Package migrate runs versioned schema migrations, written as Go functions
+// or as .sql scripts, and records the applied versions in the
+// sorm_migrations table.
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/catbugdemo/sorm"
+	"github.com/catbugdemo/sorm/log"
+	"github.com/catbugdemo/sorm/session"
+)
+
+const (
+	// TableName is the table recording the applied migrations
+	TableName = "sorm_migrations"
+	// LockName is the database lock held while migrating
+	LockName = "sorm_migrations"
+	// DefaultLockTimeout is how long Up and Rollback wait for another
+	// process to finish migrating
+	DefaultLockTimeout = time.Minute
+)
+
+var (
+	// ErrChecksumMismatch is returned when an applied migration was edited
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	// ErrIrreversible is returned when rolling back a migration without Down
+	ErrIrreversible = errors.New("migration has no down")
+	// ErrUnknownVersion is returned when a version applied to the database
+	// is not registered, such as a deleted migration
+	ErrUnknownVersion = errors.New("migration is not registered")
+)
+
+// Func changes the schema, it runs in the transaction of the session
+type Func func(s *session.Session) error
+
+// Migration is one version of the schema
+type Migration struct {
+	Version int64
+	Name    string
+	Up      Func
+	Down    Func
+	// Checksum identifies the content of the migration, the runner refuses to
+	// run once an applied migration no longer matches it. The checksum of
+	// .sql migrations is the sha256 of the up script, Go migrations have none
+	// unless set.
+	Checksum string
+}
+
+// Record is a row of the sorm_migrations table
+type Record struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Migrator applies the registered migrations in the order of their version
+type Migrator struct {
+	engine      *sorm.Engine
+	migrations  []*Migration
+	lockTimeout time.Duration
+}
+
+// New returns a Migrator running its migrations on engine
+func New(engine *sorm.Engine) *Migrator {
+	return &Migrator{engine: engine, lockTimeout: DefaultLockTimeout}
+}
+
+// SetLockTimeout sets how long Up and Rollback wait for the migrations of
+// another process, 0 waits until they are done
+func (m *Migrator) SetLockTimeout(timeout time.Duration) *Migrator {
+	m.lockTimeout = timeout
+	return m
+}
+
+// lock takes the migration lock, so concurrent deploys migrate one at a time
+func (m *Migrator) lock() (unlock func() error, err error) {
+	return m.engine.NewSession().Lock(LockName, m.lockTimeout)
+}
+
+// Add registers migration, versions must be unique and positive
+func (m *Migrator) Add(migration *Migration) error {
+	if migration.Version <= 0 {
+		return fmt.Errorf("invalid version %d of migration %s", migration.Version, migration.Name)
+	}
+	if migration.Up == nil {
+		return fmt.Errorf("migration %d has no up", migration.Version)
+	}
+	if m.find(migration.Version) != nil {
+		return fmt.Errorf("duplicate migration version %d", migration.Version)
+	}
+	m.migrations = append(m.migrations, migration)
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+	return nil
+}
+
+// Register registers a Go migration, down may be nil for an irreversible one
+func (m *Migrator) Register(version int64, name string, up, down Func) error {
+	return m.Add(&Migration{Version: version, Name: name, Up: up, Down: down})
+}
+
+// LoadFS registers the .sql scripts of dir in fsys, such as an embed.FS.
+// Scripts are named <version>_<name>.up.sql and <version>_<name>.down.sql,
+// the down script is optional. Statements are separated by semicolons, a
+// literal ? is written ??.
+func (m *Migrator) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	ups := make(map[int64]*Migration)
+	downs := make(map[int64]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		version, name, direction, err := parseFileName(entry.Name())
+		if err != nil {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		script := string(content)
+		if direction == "down" {
+			downs[version] = script
+			continue
+		}
+		if _, ok := ups[version]; ok {
+			return fmt.Errorf("duplicate migration version %d", version)
+		}
+		sum := sha256.Sum256(content)
+		ups[version] = &Migration{Version: version, Name: name, Up: sqlFunc(script), Checksum: hex.EncodeToString(sum[:])}
+	}
+	for version, script := range downs {
+		migration, ok := ups[version]
+		if !ok {
+			return fmt.Errorf("migration %d has a down script but no up script", version)
+		}
+		migration.Down = sqlFunc(script)
+	}
+	for _, migration := range ups {
+		if err = m.Add(migration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseFileName splits 0001_create_users.up.sql into 1, create_users and up
+func parseFileName(name string) (version int64, migrationName, direction string, err error) {
+	base := strings.TrimSuffix(name, ".sql")
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down"):
+		direction = "down"
+	default:
+		return 0, "", "", fmt.Errorf("migration %s is neither .up.sql nor .down.sql", name)
+	}
+	base = strings.TrimSuffix(base, "."+direction)
+	prefix := base
+	if i := strings.IndexByte(base, '_'); i >= 0 {
+		prefix, migrationName = base[:i], base[i+1:]
+	}
+	if version, err = strconv.ParseInt(prefix, 10, 64); err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("migration %s does not start with a positive version", name)
+	}
+	return version, migrationName, direction, nil
+}
+
+// sqlFunc executes the statements of script one by one
+func sqlFunc(script string) Func {
+	return func(s *session.Session) error {
+		statements, err := session.SplitStatementsSyntax(script, s.Dialect().Syntax())
+		if err != nil {
+			return err
+		}
+		for _, statement := range statements {
+			if _, err = s.Raw(statement).Exec(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+// Migrations returns the registered migrations ordered by version
+func (m *Migrator) Migrations() []*Migration {
+	return append([]*Migration(nil), m.migrations...)
+}
+
+// Applied returns the migrations recorded in the database ordered by version
+func (m *Migrator) Applied() ([]Record, error) {
+	if err := m.createTable(); err != nil {
+		return nil, err
+	}
+	rows, err := m.engine.NewSession().
+		Raw(fmt.Sprintf("SELECT version, name, checksum, applied_at FROM %s ORDER BY version", TableName)).
+		QueryRows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var records []Record
+	for rows.Next() {
+		var record Record
+		if err = rows.Scan(&record.Version, &record.Name, &record.Checksum, &record.AppliedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (m *Migrator) createTable() error {
+	_, err := m.engine.NewSession().Raw(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+		"version bigint PRIMARY KEY, name varchar(255) NOT NULL, checksum varchar(64) NOT NULL, applied_at timestamp NOT NULL)",
+		TableName)).Exec()
+	return err
+}
+
+// verify checks the applied migrations are still registered and match the
+// registered ones
+func (m *Migrator) verify(records []Record) error {
+	for _, record := range records {
+		migration := m.find(record.Version)
+		if migration == nil {
+			return fmt.Errorf("%w: version %d %s was applied", ErrUnknownVersion, record.Version, record.Name)
+		}
+		if migration.Checksum == "" && record.Checksum == "" {
+			continue
+		}
+		if migration.Checksum != record.Checksum {
+			return fmt.Errorf("%w: version %d %s was applied as %q, is now %q",
+				ErrChecksumMismatch, record.Version, record.Name, record.Checksum, migration.Checksum)
+		}
+	}
+	return nil
+}
+
+// Up applies the pending migrations in the order of their version, each one
+// in its own transaction, while holding the migration lock
+func (m *Migrator) Up() error {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	records, err := m.Applied()
+	if err != nil {
+		return err
+	}
+	if err = m.verify(records); err != nil {
+		return err
+	}
+	applied := make(map[int64]bool, len(records))
+	for _, record := range records {
+		applied[record.Version] = true
+	}
+	for _, migration := range m.migrations {
+		if applied[migration.Version] {
+			continue
+		}
+		if err = m.run(migration, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback reverts the applied migrations newer than version, the newest
+// first, Rollback(0) reverts every migration
+func (m *Migrator) Rollback(version int64) error {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	records, err := m.Applied()
+	if err != nil {
+		return err
+	}
+	if err = m.verify(records); err != nil {
+		return err
+	}
+	for i := len(records) - 1; i >= 0 && records[i].Version > version; i-- {
+		// verify found every applied version
+		migration := m.find(records[i].Version)
+		if migration.Down == nil {
+			return fmt.Errorf("%w: version %d %s", ErrIrreversible, migration.Version, migration.Name)
+		}
+		if err = m.run(migration, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run applies or reverts migration and its record in one transaction
+func (m *Migrator) run(migration *Migration, up bool) error {
+	_, err := m.engine.Transaction(func(s *session.Session) (interface{}, error) {
+		if !up {
+			if err := migration.Down(s); err != nil {
+				return nil, err
+			}
+			_, err := s.Raw(fmt.Sprintf("DELETE FROM %s WHERE version = ?", TableName), migration.Version).Exec()
+			return nil, err
+		}
+		if err := migration.Up(s); err != nil {
+			return nil, err
+		}
+		_, err := s.Raw(fmt.Sprintf("INSERT INTO %s (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)", TableName),
+			migration.Version, migration.Name, migration.Checksum, time.Now().UTC()).Exec()
+		return nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+	}
+	if up {
+		log.Infof("migration %d %s applied", migration.Version, migration.Name)
+	} else {
+		log.Infof("migration %d %s rolled back", migration.Version, migration.Name)
+	}
+	return nil
+}
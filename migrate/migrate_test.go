@@ -0,0 +1,187 @@
+package migrate
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/catbugdemo/sorm"
+	"github.com/catbugdemo/sorm/session"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_add_age.up.sql":        {Data: []byte("ALTER TABLE users ADD COLUMN age int;")},
+		"migrations/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id int);")},
+		"migrations/0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/README.md":                  {Data: []byte("ignored")},
+	}
+	m := New(nil)
+	assert.Nil(t, m.LoadFS(fsys, "migrations"))
+	migrations := m.Migrations()
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "create_users", migrations[0].Name)
+	assert.NotNil(t, migrations[0].Down)
+	assert.Nil(t, migrations[1].Down)
+	assert.Len(t, migrations[0].Checksum, 64)
+
+	err := m.Register(2, "duplicate", func(s *session.Session) error { return nil }, nil)
+	assert.NotNil(t, err)
+
+	fsys["migrations/0003_drop.down.sql"] = &fstest.MapFile{Data: []byte("SELECT 1")}
+	assert.NotNil(t, New(nil).LoadFS(fsys, "migrations"))
+}
+
+func TestParseFileName(t *testing.T) {
+	version, name, direction, err := parseFileName("20210102_add_index.down.sql")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(20210102), version)
+	assert.Equal(t, "add_index", name)
+	assert.Equal(t, "down", direction)
+
+	_, _, _, err = parseFileName("add_index.up.sql")
+	assert.NotNil(t, err)
+	_, _, _, err = parseFileName("0001_add_index.sql")
+	assert.NotNil(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	m := New(nil)
+	assert.Nil(t, m.Add(&Migration{Version: 1, Up: func(s *session.Session) error { return nil }, Checksum: "a"}))
+	assert.Nil(t, m.verify([]Record{{Version: 1, Checksum: "a"}}))
+	assert.ErrorIs(t, m.verify([]Record{{Version: 1, Checksum: "a"}, {Version: 2, Checksum: "b"}}), ErrUnknownVersion)
+	assert.ErrorIs(t, m.verify([]Record{{Version: 1, Checksum: "b"}}), ErrChecksumMismatch)
+}
+
+func sqliteEngine(t *testing.T) *sorm.Engine {
+	engine, err := sorm.NewEngine("sqlite3", filepath.Join(t.TempDir(), "sorm.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(engine.Close)
+	return engine
+}
+
+// tables lists the tables of the database but the migration ones
+func tables(t *testing.T, engine *sorm.Engine) []string {
+	var names []string
+	err := engine.NewSession().Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sorm_%' ORDER BY name").Scan(&names)
+	if err != nil && !errors.Is(err, sorm.ErrRecordNotFound) {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func versions(t *testing.T, m *Migrator) []int64 {
+	records, err := m.Applied()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []int64
+	for _, record := range records {
+		versions = append(versions, record.Version)
+	}
+	return versions
+}
+
+func TestUpRollback(t *testing.T) {
+	engine := sqliteEngine(t)
+	fsys := fstest.MapFS{
+		"migrations/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id integer PRIMARY KEY, name text);\nINSERT INTO users (name) VALUES ('a;b');")},
+		"migrations/0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/0002_create_teams.up.sql":   {Data: []byte("CREATE TABLE teams (id integer PRIMARY KEY);")},
+		"migrations/0002_create_teams.down.sql": {Data: []byte("DROP TABLE teams;")},
+	}
+	m := New(engine)
+	assert.Nil(t, m.LoadFS(fsys, "migrations"))
+	assert.Nil(t, m.Register(3, "create_orders", func(s *session.Session) error {
+		_, err := s.Raw("CREATE TABLE orders (id integer PRIMARY KEY)").Exec()
+		return err
+	}, func(s *session.Session) error {
+		_, err := s.Raw("DROP TABLE orders").Exec()
+		return err
+	}))
+
+	assert.Nil(t, m.Up())
+	assert.Equal(t, []string{"orders", "teams", "users"}, tables(t, engine))
+	assert.Equal(t, []int64{1, 2, 3}, versions(t, m))
+	var name string
+	assert.Nil(t, engine.NewSession().Raw("SELECT name FROM users").Scan(&name))
+	assert.Equal(t, "a;b", name)
+
+	// applied migrations are not run again
+	assert.Nil(t, m.Up())
+	assert.Equal(t, []int64{1, 2, 3}, versions(t, m))
+
+	assert.Nil(t, m.Rollback(1))
+	assert.Equal(t, []string{"users"}, tables(t, engine))
+	assert.Equal(t, []int64{1}, versions(t, m))
+
+	assert.Nil(t, m.Rollback(0))
+	assert.Empty(t, tables(t, engine))
+	assert.Empty(t, versions(t, m))
+}
+
+func TestUpVerify(t *testing.T) {
+	engine := sqliteEngine(t)
+	script := func(sql string) fstest.MapFS {
+		return fstest.MapFS{"migrations/0001_create_users.up.sql": {Data: []byte(sql)}}
+	}
+	m := New(engine)
+	assert.Nil(t, m.LoadFS(script("CREATE TABLE users (id integer PRIMARY KEY);"), "migrations"))
+	assert.Nil(t, m.Up())
+
+	// an applied script was edited
+	edited := New(engine)
+	assert.Nil(t, edited.LoadFS(script("CREATE TABLE users (id integer PRIMARY KEY, name text);"), "migrations"))
+	assert.Nil(t, edited.Register(2, "create_teams", func(s *session.Session) error {
+		_, err := s.Raw("CREATE TABLE teams (id integer PRIMARY KEY)").Exec()
+		return err
+	}, nil))
+	assert.ErrorIs(t, edited.Up(), ErrChecksumMismatch)
+	assert.Equal(t, []string{"users"}, tables(t, engine))
+
+	// an applied migration was deleted
+	deleted := New(engine)
+	assert.ErrorIs(t, deleted.Up(), ErrUnknownVersion)
+	assert.ErrorIs(t, deleted.Rollback(0), ErrUnknownVersion)
+
+	assert.ErrorIs(t, m.Rollback(0), ErrIrreversible)
+}
+
+func TestUpFailure(t *testing.T) {
+	engine := sqliteEngine(t)
+	m := New(engine)
+	assert.Nil(t, m.Register(1, "create_users", func(s *session.Session) error {
+		_, err := s.Raw("CREATE TABLE users (id integer PRIMARY KEY)").Exec()
+		return err
+	}, nil))
+	failure := errors.New("failure")
+	assert.Nil(t, m.Register(2, "create_teams", func(s *session.Session) error {
+		if _, err := s.Raw("CREATE TABLE teams (id integer PRIMARY KEY)").Exec(); err != nil {
+			return err
+		}
+		if _, err := s.Raw("INSERT INTO users (id) VALUES (1)").Exec(); err != nil {
+			return err
+		}
+		return failure
+	}, nil))
+
+	assert.ErrorIs(t, m.Up(), failure)
+	// the statements of the failing migration are rolled back
+	assert.Equal(t, []string{"users"}, tables(t, engine))
+	assert.Equal(t, []int64{1}, versions(t, m))
+	var count int
+	assert.Nil(t, engine.NewSession().Raw("SELECT count(*) FROM users").Scan(&count))
+	assert.Equal(t, 0, count)
+
+	// the lock was released
+	unlock, err := m.SetLockTimeout(time.Second).lock()
+	assert.Nil(t, err)
+	assert.Nil(t, unlock())
+}
@@ -0,0 +1,128 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/catbugdemo/sorm/dialect"
+)
+
+// index tag options, such as `index:idx_name,priority:2,where:deleted_at IS NULL`
+const (
+	indexPriority   = "priority"
+	indexWhere      = "where"
+	indexExpression = "expression"
+)
+
+// Index is an index declared by field tags, fields tagged with the same
+// index name make a composite index ordered by priority (default 10)
+type Index struct {
+	Name   string
+	Unique bool
+	Fields []*Field
+	// Expression is indexed instead of the columns, such as lower(email)
+	Expression string
+	// Where makes a partial index, such as deleted_at IS NULL
+	Where string
+
+	priorities map[*Field]int
+}
+
+// Columns returns the indexed columns in order
+func (index *Index) Columns() []string {
+	columns := make([]string, 0, len(index.Fields))
+	for _, field := range index.Fields {
+		columns = append(columns, field.SqlName)
+	}
+	return columns
+}
+
+// Dialect returns the index in the form the dialects render
+func (index *Index) Dialect(tableName string) *dialect.Index {
+	return &dialect.Index{
+		Table:      tableName,
+		Name:       index.Name,
+		Unique:     index.Unique,
+		Columns:    index.Columns(),
+		Expression: index.Expression,
+		Where:      index.Where,
+	}
+}
+
+// GetIndex returns the index named name
+func (schema *Schema) GetIndex(name string) *Index {
+	for _, index := range schema.Indexes {
+		if index.Name == name {
+			return index
+		}
+	}
+	return nil
+}
+
+// parseIndexes builds Schema.Indexes from the index tags of the fields
+func (schema *Schema) parseIndexes() error {
+	for _, field := range schema.Fields {
+		for _, setting := range field.indexSettings {
+			name, options, err := parseIndexOptions(setting.Value)
+			if err != nil {
+				return fmt.Errorf("field %s: %v", field.Name, err)
+			}
+			if name == "" {
+				name = fmt.Sprintf("idx_%s_%s", schema.SqlName, field.SqlName)
+			}
+
+			index := schema.GetIndex(name)
+			if index == nil {
+				index = &Index{Name: name, priorities: make(map[*Field]int)}
+				schema.Indexes = append(schema.Indexes, index)
+			}
+			index.Unique = index.Unique || setting.Key == tagUniqueIndex
+			index.priorities[field] = 10
+			for key, value := range options {
+				switch key {
+				case indexPriority:
+					priority, err := strconv.Atoi(value)
+					if err != nil {
+						return fmt.Errorf("field %s: priority must be an integer, got %q", field.Name, value)
+					}
+					index.priorities[field] = priority
+				case indexWhere:
+					index.Where = value
+				case indexExpression:
+					index.Expression = value
+				}
+			}
+			index.Fields = append(index.Fields, field)
+		}
+	}
+	for _, index := range schema.Indexes {
+		sort.SliceStable(index.Fields, func(i, j int) bool {
+			return index.priorities[index.Fields[i]] < index.priorities[index.Fields[j]]
+		})
+	}
+	return nil
+}
+
+// parseIndexOptions splits `idx_name,priority:2,where:a IN (1,2)`, a comma only
+// starts a new option when it is followed by an option key
+func parseIndexOptions(value string) (string, map[string]string, error) {
+	options := make(map[string]string)
+	parts := strings.Split(value, ",")
+	name := strings.TrimSpace(parts[0])
+	var key string
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, ":", 2)
+		switch k := normalizeTagKey(kv[0]); {
+		case len(kv) == 2 && (k == indexPriority || k == indexWhere || k == indexExpression):
+			key = k
+			options[key] = strings.TrimSpace(kv[1])
+		case key == indexWhere || key == indexExpression:
+			options[key] += "," + part
+		default:
+			return "", nil, fmt.Errorf("unknown index option %q", strings.TrimSpace(part))
+		}
+	}
+	return name, options, nil
+}
@@ -0,0 +1,40 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/catbugdemo/sorm/dialect"
+	"github.com/catbugdemo/sorm/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type Account struct {
+	Id        int64
+	TenantId  int64  `sorm:"uniqueIndex:idx_tenant_email,priority:1"`
+	Email     string `sorm:"uniqueIndex:idx_tenant_email,priority:2;index:idx_lower_email,expression:lower(email)"`
+	Status    string `sorm:"index:idx_active,where:status IN ('a','b')"`
+	CreatedAt int64  `sorm:"index"`
+	DeletedAt *int64 `sorm:"index,where:deleted_at IS NULL"`
+}
+
+func TestParseIndexes(t *testing.T) {
+	schema := Parse(&Account{}, TestDial)
+	assert.Len(t, schema.Indexes, 5)
+	assert.Equal(t, "deleted_at IS NULL", schema.GetIndex("idx_account_deleted_at").Where)
+
+	composite := schema.GetIndex("idx_tenant_email")
+	assert.True(t, composite.Unique)
+	assert.Equal(t, []string{"tenant_id", "email"}, composite.Columns())
+
+	assert.Equal(t, "lower(email)", schema.GetIndex("idx_lower_email").Expression)
+	assert.Equal(t, "status IN ('a','b')", schema.GetIndex("idx_active").Where)
+
+	created := schema.GetIndex("idx_account_created_at")
+	assert.False(t, created.Unique)
+	assert.Equal(t, &dialect.Index{Table: "account", Name: "idx_account_created_at", Columns: []string{"created_at"}}, created.Dialect("account"))
+
+	_, err := ParseWithNamer(&struct {
+		Name string `sorm:"index:idx_name,sort:desc"`
+	}{}, TestDial, DefaultNamingStrategy)
+	assert.ErrorIs(t, err, log.ErrInvalidModel)
+}
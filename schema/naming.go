@@ -0,0 +1,76 @@
+package schema
+
+import "strings"
+
+// Namer converts the names of Go types and fields to table and column names
+type Namer interface {
+	TableName(name string) string
+	ColumnName(name string) string
+}
+
+// DefaultNamingStrategy names tables and columns with GetUnderlineName
+var DefaultNamingStrategy Namer = NamingStrategy{}
+
+// NamingStrategy is the default Namer, the zero value names UserTest user_test
+type NamingStrategy struct {
+	// TablePrefix is prepended to every table name: t_user_test
+	TablePrefix string
+	// PluralTable pluralises table names: user_tests
+	PluralTable bool
+	// Initialisms keeps runs of upper case letters in one word: UserID -> user_id
+	Initialisms bool
+}
+
+var _ Namer = NamingStrategy{}
+
+func (ns NamingStrategy) TableName(name string) string {
+	name = ns.underline(name)
+	if ns.PluralTable {
+		name = plural(name)
+	}
+	return ns.TablePrefix + name
+}
+
+func (ns NamingStrategy) ColumnName(name string) string {
+	return ns.underline(name)
+}
+
+func (ns NamingStrategy) underline(name string) string {
+	if !ns.Initialisms {
+		return GetUnderlineName(name)
+	}
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if isUpper(c) && i > 0 {
+			// a new word starts after a lower case letter, or at the last
+			// upper case letter of a run followed by a lower case one: HTTPServer
+			prev := name[i-1]
+			if !isUpper(prev) && prev != '_' || i+1 < len(name) && isLower(name[i+1]) && isUpper(prev) {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteByte(c)
+	}
+	return strings.ToLower(sb.String())
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+func isLower(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+// plural is a small english pluraliser: user -> users, category -> categories
+func plural(name string) string {
+	switch {
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "z"),
+		strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsAny(name[len(name)-2:len(name)-1], "aeiou"):
+		return name[:len(name)-1] + "ies"
+	}
+	return name + "s"
+}
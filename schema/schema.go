@@ -1,7 +1,9 @@
 package schema
 
 import (
+	"fmt"
 	"github.com/catbugdemo/sorm/dialect"
+	"github.com/catbugdemo/sorm/log"
 	"go/ast"
 	"reflect"
 	"strings"
@@ -13,7 +15,57 @@ type Field struct {
 	Name    string
 	SqlName string
 	Type    string
-	Tag     string
+	// Tag is the raw sorm tag, parsed into the properties below
+	Tag string
+	// Nullable reports whether the column accepts NULL: pointer fields and
+	// scanner types such as sql.NullString, other columns are NOT NULL
+	Nullable bool
+
+	PrimaryKey    bool
+	AutoIncrement bool
+	Unique        bool
+	NotNull       bool
+	// Size is the length of string columns, 0 for the dialect default
+	Size int
+	// Default is the SQL default expression of the column, such as 'x' or 0
+	Default string
+	// indexSettings are the index and uniqueIndex tags, see Schema.Indexes
+	indexSettings []TagSetting
+	// Index is the path of the field in the model, fields of embedded
+	// structs have one entry per level, see reflect.Value.FieldByIndex
+	Index []int
+}
+
+// ValueOf returns the field of dest, the zero Value is returned when a nil
+// embedded pointer hides it
+func (field *Field) ValueOf(dest reflect.Value) reflect.Value {
+	dest = reflect.Indirect(dest)
+	for i, index := range field.Index {
+		if i > 0 && dest.Kind() == reflect.Ptr {
+			if dest.IsNil() {
+				return reflect.Value{}
+			}
+			dest = dest.Elem()
+		}
+		dest = dest.Field(index)
+	}
+	return dest
+}
+
+// Addressable returns the settable field of dest, allocating the nil
+// embedded pointers on the way
+func (field *Field) Addressable(dest reflect.Value) reflect.Value {
+	dest = reflect.Indirect(dest)
+	for i, index := range field.Index {
+		if i > 0 && dest.Kind() == reflect.Ptr {
+			if dest.IsNil() {
+				dest.Set(reflect.New(dest.Type().Elem()))
+			}
+			dest = dest.Elem()
+		}
+		dest = dest.Field(index)
+	}
+	return dest
 }
 
 // Schema represents a table of database
@@ -25,59 +77,200 @@ type Schema struct {
 	FieldNames  []string
 	fieldMap    map[string]*Field
 	FieldSqlMap map[string]string
+	// Indexes are declared by the index and uniqueIndex tags of the fields
+	Indexes []*Index
+	// PrimaryFields are the fields tagged `sorm:"primaryKey"`, or the column
+	// named id when no field is tagged
+	PrimaryFields []*Field
 }
 
 func (schema *Schema) GetField(name string) *Field {
 	return schema.fieldMap[name]
 }
 
+// GetFieldBySqlName returns the field of the column sqlName
+func (schema *Schema) GetFieldBySqlName(sqlName string) *Field {
+	return schema.fieldMap[schema.FieldSqlMap[sqlName]]
+}
+
+// PrimaryField returns the first of PrimaryFields, nil without primary key
+func (schema *Schema) PrimaryField() *Field {
+	if len(schema.PrimaryFields) == 0 {
+		return nil
+	}
+	return schema.PrimaryFields[0]
+}
+
+// parsePrimaryFields sets PrimaryFields
+func (schema *Schema) parsePrimaryFields() {
+	var id *Field
+	for _, field := range schema.Fields {
+		if field.PrimaryKey {
+			schema.PrimaryFields = append(schema.PrimaryFields, field)
+		}
+		if field.SqlName == "id" {
+			id = field
+		}
+	}
+	if len(schema.PrimaryFields) == 0 && id != nil {
+		schema.PrimaryFields = []*Field{id}
+	}
+}
+
 // Parse 将任意的对象解析为 Schema 实例
+// it panics on an invalid model, such as a malformed sorm tag, see ParseWithNamer
 func Parse(dest interface{}, d dialect.Dialect) *Schema {
+	schema, err := ParseWithNamer(dest, d, DefaultNamingStrategy)
+	if err != nil {
+		panic(err.Error())
+	}
+	return schema
+}
+
+// ParseWithNamer parses dest like Parse, naming the table and the columns
+// without a db tag with namer. An invalid model, such as a malformed sorm
+// tag, a field without sql type or an invalid index, is reported as an
+// error matching log.ErrInvalidModel.
+func ParseWithNamer(dest interface{}, d dialect.Dialect, namer Namer) (*Schema, error) {
 	modelType := reflect.Indirect(reflect.ValueOf(dest)).Type()
 	schema := &Schema{
 		Model:       dest,
 		Name:        modelType.Name(),
-		SqlName:     GetUnderlineName(modelType.Name()),
+		SqlName:     namer.TableName(modelType.Name()),
 		fieldMap:    make(map[string]*Field),
 		FieldSqlMap: make(map[string]string),
 	}
 
-	for i := 0; i < modelType.NumField(); i++ {
-		p := modelType.Field(i)
+	if err := schema.parseFields(modelType, nil, "", "", false, d, namer); err != nil {
+		return nil, err
+	}
+	schema.parsePrimaryFields()
+	if err := schema.parseIndexes(); err != nil {
+		return nil, fmt.Errorf("%w: invalid index of %s: %v", log.ErrInvalidModel, schema.Name, err)
+	}
+	return schema, nil
+}
+
+// parseFields appends the columns of typ, the fields of anonymous structs and
+// of struct fields tagged `sorm:"embedded"` are flattened into the schema,
+// with the column names prefixed by `sorm:"prefix:addr_"`. The columns of
+// structs embedded through a pointer are nullable.
+func (schema *Schema) parseFields(typ reflect.Type, index []int, namePrefix, sqlPrefix string, nullable bool, d dialect.Dialect, namer Namer) error {
+	for i := 0; i < typ.NumField(); i++ {
+		p := typ.Field(i)
 		// Anonymous 是否匿名字段， IsExported 是否大写
-		if !p.Anonymous && ast.IsExported(p.Name) {
-			field := &Field{
-				Name: p.Name,
-				Type: d.DataTypeOf(reflect.Indirect(reflect.New(p.Type))),
-			}
-			if v, ok := p.Tag.Lookup("sorm"); ok { // table 关键字,如 : primary key
-				field.Tag = v
+		if !ast.IsExported(p.Name) {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+		tag := p.Tag.Get("sorm")
+		settings, err := ParseTag(tag)
+		if err != nil {
+			return fmt.Errorf("%w: invalid sorm tag %q of field %s.%s: %v", log.ErrInvalidModel, tag, typ.Name(), p.Name, err)
+		}
+		flags := ParseTagSetting(tag)
+		if _, ok := flags[tagIgnore]; ok {
+			continue
+		}
+		if embedded := indirectType(p.Type); embedded.Kind() == reflect.Struct && embedded != timeType && !isScanner(embedded) {
+			if _, ok := flags[tagEmbedded]; p.Anonymous || ok {
+				name := namePrefix
+				if !p.Anonymous {
+					name += p.Name + "."
+				}
+				if err = schema.parseFields(embedded, fieldIndex, name, sqlPrefix+flags[tagPrefix], nullable || p.Type.Kind() == reflect.Ptr, d, namer); err != nil {
+					return err
+				}
+				continue
 			}
-			if v, ok := p.Tag.Lookup("db"); ok {
-				field.SqlName = v
+		}
+		if p.Anonymous {
+			continue
+		}
+
+		field := &Field{
+			Name:     namePrefix + p.Name,
+			Tag:      tag,
+			Nullable: nullable || isNullable(p.Type),
+			Index:    fieldIndex,
+		}
+		if err = applyTag(field, p.Type, settings); err != nil {
+			return fmt.Errorf("%w: invalid sorm tag %q of field %s.%s: %v", log.ErrInvalidModel, tag, typ.Name(), p.Name, err)
+		}
+		if field.Type == "" {
+			if field.Type, err = dataTypeOf(d, p.Type, field.Size, field.AutoIncrement, typ.Name()+"."+p.Name); err != nil {
+				return err
 			}
-			schema.Fields = append(schema.Fields, field)
-			schema.FieldNames = append(schema.FieldNames, field.SqlName)
-			schema.fieldMap[p.Name] = field // fieldMap 通过名称作为键值,能够快速查找 field
-			schema.FieldSqlMap[field.SqlName] = p.Name
 		}
+		if v, ok := p.Tag.Lookup("db"); ok && v != "" {
+			field.SqlName = sqlPrefix + v
+		} else {
+			field.SqlName = sqlPrefix + namer.ColumnName(p.Name)
+		}
+		schema.Fields = append(schema.Fields, field)
+		schema.FieldNames = append(schema.FieldNames, field.SqlName)
+		schema.fieldMap[field.Name] = field // fieldMap 通过名称作为键值,能够快速查找 field
+		schema.FieldSqlMap[field.SqlName] = field.Name
 	}
-	return schema
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
 }
 
+// RecordValues returns the columns and values of the fields of dest, blank
+// fields are left out so the database applies its defaults
 func (schema *Schema) RecordValues(dest interface{}) ([]string, []interface{}) {
+	return schema.SelectValues(dest, nil, nil)
+}
+
+// SelectValues returns the columns and values of dest like RecordValues,
+// the selected fields are kept even when blank, such as false or 0, and the
+// omitted ones are left out. Fields are named by column or by field name,
+// "*" selects every field.
+func (schema *Schema) SelectValues(dest interface{}, selects, omits []string) ([]string, []interface{}) {
 	destValue := reflect.Indirect(reflect.ValueOf(dest))
 	var fieldSqlNames []string
 	var fieldValues []interface{}
 	for _, field := range schema.Fields {
-		if !IsBlank(destValue.FieldByName(field.Name)) {
-			fieldSqlNames = append(fieldSqlNames, field.SqlName)
-			fieldValues = append(fieldValues, destValue.FieldByName(field.Name).Interface())
+		if field.named(omits) {
+			continue
 		}
+		value := field.ValueOf(destValue)
+		switch {
+		case value.IsValid() && !IsBlank(value):
+		case field.named(selects):
+			if !value.IsValid() {
+				// a nil embedded pointer hides the field
+				fieldSqlNames = append(fieldSqlNames, field.SqlName)
+				fieldValues = append(fieldValues, nil)
+				continue
+			}
+		default:
+			continue
+		}
+		fieldSqlNames = append(fieldSqlNames, field.SqlName)
+		fieldValues = append(fieldValues, value.Interface())
 	}
 	return fieldSqlNames, fieldValues
 }
 
+// named reports whether names holds the column or the name of field
+func (field *Field) named(names []string) bool {
+	for _, name := range names {
+		if name == "*" || name == field.SqlName || name == field.Name {
+			return true
+		}
+	}
+	return false
+}
+
 // GetUnderlineName generate table_
 func GetUnderlineName(name string) string {
 	var index, count int
@@ -111,10 +304,11 @@ func IsBlank(value reflect.Value) bool {
 	case reflect.Float32, reflect.Float64:
 		return value.Float() == 0
 	case reflect.Interface, reflect.Ptr:
+		return value.IsNil()
+	case reflect.Struct:
 		if t, ok := value.Interface().(time.Time); ok {
 			return t.IsZero()
 		}
-		return value.IsNil()
 	}
 	return reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
 }
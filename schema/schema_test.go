@@ -1,8 +1,12 @@
 package schema
 
 import (
+	"database/sql"
+	"database/sql/driver"
 	"github.com/catbugdemo/sorm/dialect"
+	"github.com/catbugdemo/sorm/log"
 	"github.com/stretchr/testify/assert"
+	"reflect"
 	"testing"
 )
 
@@ -16,7 +20,143 @@ var TestDial, _ = dialect.GetDialect("sqlite3")
 func TestParse(t *testing.T) {
 	schema := Parse(&User{}, TestDial)
 
-	assert.Equal(t, "User", schema.SqlName)
+	assert.Equal(t, "user", schema.SqlName)
 	assert.Len(t, schema.Fields, 2)
-	assert.Equal(t, schema.GetField("SqlName").Type, "text")
+	assert.Equal(t, schema.GetField("Name").Type, "text")
+	assert.Equal(t, "age", schema.GetField("Age").SqlName)
+	assert.Equal(t, []string{"name", "age"}, schema.FieldNames)
+}
+
+type HTTPUserID struct {
+	UserID    int
+	APIKey    string
+	CreatedAt int
+}
+
+func TestNamingStrategy(t *testing.T) {
+	schema := Parse(&HTTPUserID{}, TestDial)
+	assert.Equal(t, "h_t_t_p_user_i_d", schema.SqlName)
+	assert.Equal(t, "user_i_d", schema.GetField("UserID").SqlName)
+
+	namer := NamingStrategy{TablePrefix: "t_", PluralTable: true, Initialisms: true}
+	schema, err := ParseWithNamer(&HTTPUserID{}, TestDial, namer)
+	assert.Nil(t, err)
+	assert.Equal(t, "t_http_user_ids", schema.SqlName)
+	assert.Equal(t, []string{"user_id", "api_key", "created_at"}, schema.FieldNames)
+
+	assert.Equal(t, "categories", namer.TableName("Category")[2:])
+	assert.Equal(t, "boxes", namer.TableName("Box")[2:])
+	assert.Equal(t, "keys", namer.TableName("Key")[2:])
+}
+
+type BaseModel struct {
+	Id          int `db:"id"`
+	CreatedTime int `db:"created_time"`
+}
+
+type Address struct {
+	Street string
+	City   string
+}
+
+type Meta struct {
+	Version int
+}
+
+type Customer struct {
+	BaseModel
+	*Meta
+	Name string
+	Addr Address `sorm:"embedded;prefix:addr_"`
+}
+
+func TestParseEmbedded(t *testing.T) {
+	schema := Parse(&Customer{}, TestDial)
+	assert.Equal(t, []string{"id", "created_time", "version", "name", "addr_street", "addr_city"}, schema.FieldNames)
+	assert.Equal(t, "Addr.City", schema.GetFieldBySqlName("addr_city").Name)
+
+	c := Customer{Name: "Tom", Addr: Address{City: "Paris"}}
+	c.Id = 1
+	names, values := schema.RecordValues(&c)
+	assert.Equal(t, []string{"id", "name", "addr_city"}, names)
+	assert.Equal(t, []interface{}{1, "Tom", "Paris"}, values)
+
+	dest := reflect.ValueOf(&c).Elem()
+	assert.False(t, schema.GetField("Version").ValueOf(dest).IsValid())
+	schema.GetField("Version").Addressable(dest).SetInt(2)
+	assert.Equal(t, 2, c.Meta.Version)
+}
+
+type Money struct {
+	Cents int64
+}
+
+func (m *Money) Scan(value interface{}) error {
+	m.Cents = value.(int64)
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.Cents, nil
+}
+
+type Wallet struct {
+	Id      int64
+	Balance Money
+	Limit   *Money
+	Note    sql.NullString
+	Label   *string
+	Payload Money `sorm:"type:jsonb;unique"`
+}
+
+func TestParseNullable(t *testing.T) {
+	schema := Parse(&Wallet{}, TestDial)
+	assert.Equal(t, "bigint", schema.GetField("Balance").Type)
+	assert.False(t, schema.GetField("Balance").Nullable)
+	assert.True(t, schema.GetField("Limit").Nullable)
+	assert.Equal(t, "text", schema.GetField("Note").Type)
+	assert.True(t, schema.GetField("Note").Nullable)
+	assert.Equal(t, "text", schema.GetField("Label").Type)
+	assert.True(t, schema.GetField("Label").Nullable)
+	assert.False(t, schema.GetField("Id").Nullable)
+	assert.Equal(t, "jsonb", schema.GetField("Payload").Type)
+	assert.True(t, schema.GetField("Payload").Unique)
+
+	RegisterType(Money{}, "numeric(20,2)")
+	defer delete(registeredTypes, reflect.TypeOf(Money{}))
+	assert.Equal(t, "numeric(20,2)", Parse(&Wallet{}, TestDial).GetField("Limit").Type)
+
+	label := "x"
+	names, _ := schema.RecordValues(&Wallet{Label: &label})
+	assert.Equal(t, []string{"label"}, names)
+
+	_, err := ParseWithNamer(&struct{ Addr Address }{}, TestDial, DefaultNamingStrategy)
+	assert.ErrorIs(t, err, log.ErrInvalidModel)
+	assert.Panics(t, func() { Parse(&struct{ Addr Address }{}, TestDial) })
+}
+
+func TestSelectValues(t *testing.T) {
+	schema := Parse(&Customer{}, TestDial)
+	c := Customer{Name: "Tom"}
+	names, values := schema.SelectValues(&c, []string{"id", "Addr.City", "version"}, []string{"name"})
+	assert.Equal(t, []string{"id", "version", "addr_city"}, names)
+	assert.Equal(t, []interface{}{0, nil, ""}, values)
+
+	names, _ = schema.SelectValues(&c, []string{"*"}, []string{"created_time"})
+	assert.Equal(t, []string{"id", "version", "name", "addr_street", "addr_city"}, names)
+}
+
+func TestPrimaryFields(t *testing.T) {
+	assert.Equal(t, "Id", Parse(&Customer{}, TestDial).PrimaryField().Name)
+	assert.Nil(t, Parse(&Address{}, TestDial).PrimaryField())
+
+	type Member struct {
+		Id     int64
+		TeamId int64 `sorm:"primaryKey"`
+		UserId int64 `sorm:"primaryKey"`
+	}
+	fields := Parse(&Member{}, TestDial).PrimaryFields
+	assert.Equal(t, 2, len(fields))
+	assert.Equal(t, "team_id", fields[0].SqlName)
+	assert.Equal(t, "user_id", fields[1].SqlName)
 }
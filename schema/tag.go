@@ -0,0 +1,139 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// sorm tag keys, matched case insensitively with spaces and underscores
+// removed, so `primaryKey`, `primary_key` and `primary key` are the same key
+const (
+	tagIgnore        = "-"
+	tagPrimaryKey    = "primarykey"
+	tagAutoIncrement = "autoincrement"
+	tagUnique        = "unique"
+	tagNotNull       = "notnull"
+	tagSize          = "size"
+	tagDefault       = "default"
+	tagType          = "type"
+	tagIndex         = "index"
+	tagUniqueIndex   = "uniqueindex"
+	tagEmbedded      = "embedded"
+	tagPrefix        = "prefix"
+)
+
+// TagSetting is one item of a sorm tag, such as size:64
+type TagSetting struct {
+	Key   string
+	Value string
+}
+
+// ParseTag splits a sorm tag such as `primaryKey;size:64;default:'a;b'` into
+// its settings, semicolons inside single quotes do not split
+func ParseTag(tag string) ([]TagSetting, error) {
+	var settings []TagSetting
+	var quoted bool
+	start := 0
+	for i := 0; i <= len(tag); i++ {
+		if i < len(tag) {
+			if tag[i] == '\'' {
+				quoted = !quoted
+			}
+			if tag[i] != ';' || quoted {
+				continue
+			}
+		}
+		item := strings.TrimSpace(tag[start:i])
+		start = i + 1
+		if item == "" {
+			continue
+		}
+		// the key ends at : or at the , of unnamed index options: index,where:...
+		setting := TagSetting{Key: normalizeTagKey(item)}
+		if sep := strings.IndexAny(item, ":,"); sep >= 0 {
+			setting.Key = normalizeTagKey(item[:sep])
+			if item[sep] == ':' {
+				sep++
+			}
+			setting.Value = strings.TrimSpace(item[sep:])
+		}
+		settings = append(settings, setting)
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	return settings, nil
+}
+
+// ParseTagSetting parses a sorm tag such as `embedded;prefix:addr_` into a map,
+// a key without value maps to itself
+func ParseTagSetting(tag string) map[string]string {
+	settings := make(map[string]string)
+	items, _ := ParseTag(tag)
+	for _, item := range items {
+		if item.Value == "" {
+			settings[item.Key] = item.Key
+		} else {
+			settings[item.Key] = item.Value
+		}
+	}
+	return settings
+}
+
+func normalizeTagKey(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	return strings.NewReplacer(" ", "", "_", "").Replace(key)
+}
+
+// applyTag validates the settings of a column and sets the matching
+// properties of field
+func applyTag(field *Field, typ reflect.Type, settings []TagSetting) error {
+	for _, setting := range settings {
+		switch setting.Key {
+		case tagPrimaryKey, tagAutoIncrement, tagUnique, tagNotNull:
+			if setting.Value != "" {
+				return fmt.Errorf("%s takes no value", setting.Key)
+			}
+		case tagSize, tagDefault, tagType:
+			if setting.Value == "" {
+				return fmt.Errorf("%s needs a value", setting.Key)
+			}
+		}
+
+		switch setting.Key {
+		case tagPrimaryKey:
+			field.PrimaryKey = true
+		case tagAutoIncrement:
+			switch indirectType(typ).Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			default:
+				return fmt.Errorf("autoIncrement needs an integer field, got %s", typ)
+			}
+			field.AutoIncrement = true
+		case tagUnique:
+			field.Unique = true
+		case tagNotNull:
+			field.NotNull = true
+		case tagSize:
+			size, err := strconv.Atoi(setting.Value)
+			if err != nil || size <= 0 {
+				return fmt.Errorf("size must be a positive integer, got %q", setting.Value)
+			}
+			field.Size = size
+		case tagDefault:
+			field.Default = setting.Value
+		case tagType:
+			field.Type = setting.Value
+		case tagIndex, tagUniqueIndex:
+			field.indexSettings = append(field.indexSettings, setting)
+		case tagEmbedded, tagPrefix:
+			return fmt.Errorf("%s only applies to struct fields", setting.Key)
+		default:
+			return fmt.Errorf("unknown setting %q", setting.Key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/catbugdemo/sorm/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type Article struct {
+	Id      int64  `sorm:"primaryKey;autoIncrement"`
+	Title   string `sorm:"size:64;notNull;unique"`
+	Status  string `sorm:"default:'a;b'"`
+	Body    string `sorm:"type:jsonb;index:idx_body"`
+	Legacy  int    `sorm:"primary key"`
+	Ignored string `sorm:"-"`
+}
+
+func TestParseTag(t *testing.T) {
+	settings, err := ParseTag("primaryKey; size:64 ;default:'a;b';index")
+	assert.Nil(t, err)
+	assert.Equal(t, []TagSetting{{"primarykey", ""}, {"size", "64"}, {"default", "'a;b'"}, {"index", ""}}, settings)
+
+	_, err = ParseTag("default:'x")
+	assert.NotNil(t, err)
+
+	schema := Parse(&Article{}, TestDial)
+	assert.Equal(t, []string{"id", "title", "status", "body", "legacy"}, schema.FieldNames)
+
+	id := schema.GetField("Id")
+	assert.True(t, id.PrimaryKey)
+	assert.True(t, id.AutoIncrement)
+	assert.Equal(t, "integer", id.Type)
+	assert.Equal(t, id, schema.PrimaryField())
+
+	title := schema.GetField("Title")
+	assert.Equal(t, 64, title.Size)
+	assert.True(t, title.NotNull)
+	assert.True(t, title.Unique)
+
+	assert.Equal(t, "'a;b'", schema.GetField("Status").Default)
+	assert.Equal(t, "jsonb", schema.GetField("Body").Type)
+	assert.Equal(t, []string{"body"}, schema.GetIndex("idx_body").Columns())
+	assert.True(t, schema.GetField("Legacy").PrimaryKey)
+}
+
+func TestParseTagInvalid(t *testing.T) {
+	_, err := ParseWithNamer(&struct {
+		Id int `sorm:"primary key not null"`
+	}{}, TestDial, DefaultNamingStrategy)
+	assert.ErrorIs(t, err, log.ErrInvalidModel)
+	assert.EqualError(t, err, `invalid model: invalid sorm tag "primary key not null" of field .Id: unknown setting "primarykeynotnull"`)
+
+	for _, model := range []interface{}{
+		&struct {
+			Name string `sorm:"size:abc"`
+		}{},
+		&struct {
+			Name string `sorm:"autoIncrement"`
+		}{},
+		&struct {
+			Name string `sorm:"unique:yes"`
+		}{},
+	} {
+		_, err = ParseWithNamer(model, TestDial, DefaultNamingStrategy)
+		assert.ErrorIs(t, err, log.ErrInvalidModel)
+	}
+	assert.Panics(t, func() {
+		Parse(&struct {
+			Name string `sorm:"size:abc"`
+		}{}, TestDial)
+	})
+}
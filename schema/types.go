@@ -0,0 +1,101 @@
+package schema
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/catbugdemo/sorm/dialect"
+	"github.com/catbugdemo/sorm/log"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+	// nullTypes maps the database/sql null types to the value they wrap
+	nullTypes = map[reflect.Type]interface{}{
+		reflect.TypeOf(sql.NullString{}):  "",
+		reflect.TypeOf(sql.NullBool{}):    false,
+		reflect.TypeOf(sql.NullByte{}):    byte(0),
+		reflect.TypeOf(sql.NullInt16{}):   int16(0),
+		reflect.TypeOf(sql.NullInt32{}):   int32(0),
+		reflect.TypeOf(sql.NullInt64{}):   int64(0),
+		reflect.TypeOf(sql.NullFloat64{}): float64(0),
+		reflect.TypeOf(sql.NullTime{}):    time.Time{},
+	}
+
+	typesMu         sync.RWMutex
+	registeredTypes = map[reflect.Type]string{}
+)
+
+// RegisterType maps the type of value to the column type dataType for every
+// dialect, use it for sql.Scanner and driver.Valuer types the dialects do not
+// know, such as RegisterType(Money{}, "numeric(20,4)"). The `sorm:"type:..."`
+// tag takes precedence over the registration.
+func RegisterType(value interface{}, dataType string) {
+	typesMu.Lock()
+	defer typesMu.Unlock()
+	registeredTypes[indirectType(reflect.TypeOf(value))] = dataType
+}
+
+func registeredType(typ reflect.Type) (string, bool) {
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+	dataType, ok := registeredTypes[typ]
+	return dataType, ok
+}
+
+// isScanner reports whether typ handles its own conversion through
+// sql.Scanner or driver.Valuer, such types are columns, never embedded structs
+func isScanner(typ reflect.Type) bool {
+	ptr := reflect.PtrTo(typ)
+	return ptr.Implements(scannerType) || ptr.Implements(valuerType)
+}
+
+// isNullable reports whether a field of type typ can hold NULL: pointers,
+// and scanner types whose zero value is NULL such as sql.NullString
+func isNullable(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Interface {
+		return true
+	}
+	if _, ok := nullTypes[typ]; ok {
+		return true
+	}
+	if valuer, ok := reflect.New(typ).Interface().(driver.Valuer); ok {
+		v, err := valuer.Value()
+		return err == nil && v == nil
+	}
+	return false
+}
+
+// dataTypeOf returns the column type of a field of type typ
+func dataTypeOf(d dialect.Dialect, typ reflect.Type, size int, autoIncrement bool, fieldName string) (string, error) {
+	typ = indirectType(typ)
+	if dataType, ok := registeredType(typ); ok {
+		return dataType, nil
+	}
+	if !isScanner(typ) {
+		if typ.Kind() == reflect.Struct && typ != timeType {
+			return "", fmt.Errorf("%w: invalid sql type %s of field %s, register it with schema.RegisterType or set `sorm:\"type:...\"`", log.ErrInvalidModel, typ, fieldName)
+		}
+		return d.ColumnTypeOf(reflect.New(typ).Elem(), size, autoIncrement), nil
+	}
+
+	if sample, ok := nullTypes[typ]; ok {
+		return d.ColumnTypeOf(reflect.ValueOf(sample), size, autoIncrement), nil
+	}
+	// the driver value of the zero value tells the column type, as for uuid.UUID
+	if valuer, ok := reflect.New(typ).Interface().(driver.Valuer); ok {
+		if v, err := valuer.Value(); err == nil && v != nil {
+			return d.ColumnTypeOf(reflect.ValueOf(v), size, autoIncrement), nil
+		}
+	}
+	if typ.Kind() != reflect.Struct {
+		return d.ColumnTypeOf(reflect.New(typ).Elem(), size, autoIncrement), nil
+	}
+	return "", fmt.Errorf("%w: unknown sql type of %s field %s, register it with schema.RegisterType or set `sorm:\"type:...\"`", log.ErrInvalidModel, typ, fieldName)
+}
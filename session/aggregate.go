@@ -0,0 +1,77 @@
+package session
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/catbugdemo/sorm/clause"
+	"github.com/catbugdemo/sorm/log"
+)
+
+// Sum scans the sum of column over the matched rows into dest. The sum of no
+// rows is NULL, scan into a pointer or a sql.Null* type to tell it from 0.
+func (s *Session) Sum(column string, dest interface{}) error {
+	return s.aggregate("sum", column, dest)
+}
+
+// Avg scans the average of column over the matched rows into dest, NULL
+// when no row matches
+func (s *Session) Avg(column string, dest interface{}) error {
+	return s.aggregate("avg", column, dest)
+}
+
+// Min scans the smallest value of column into dest, NULL when no row matches
+func (s *Session) Min(column string, dest interface{}) error {
+	return s.aggregate("min", column, dest)
+}
+
+// Max scans the largest value of column into dest, NULL when no row matches
+func (s *Session) Max(column string, dest interface{}) error {
+	return s.aggregate("max", column, dest)
+}
+
+func (s *Session) aggregate(fn, column string, dest interface{}) error {
+	s.from()
+	s.clause.Set(clause.SELECT, []string{fmt.Sprintf("%s(%s)", fn, column)})
+	sql, vars := s.clause.Build(clause.WITH, clause.SELECT, clause.TABLE, clause.JOIN, clause.WHERE)
+	if err := s.raw(sql, vars).QueryRow().Scan(dest); err != nil {
+		return s.wrapErr(err)
+	}
+	return nil
+}
+
+// Pluck scans the values of column of the matched rows into dest, a pointer
+// to a slice, it returns ErrRecordNotFound when no row matches and
+// ErrValuesNotPointer for any other dest
+//
+//	var names []string
+//	s.Model(&User{}).Where("age > ?", 18).Pluck("name", &names)
+func (s *Session) Pluck(column string, dest interface{}) error {
+	pointer := reflect.ValueOf(dest)
+	if pointer.Kind() != reflect.Ptr || pointer.IsNil() || pointer.Elem().Kind() != reflect.Slice {
+		s.Clear()
+		return fmt.Errorf("%w: Pluck needs a pointer to a slice, got %T", log.ErrValuesNotPointer, dest)
+	}
+	slice := pointer.Elem()
+	s.from()
+	s.setSelect([]string{column})
+	rows, err := s.raw(s.selectSQL()).QueryRows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		value := reflect.New(slice.Type().Elem())
+		if err = rows.Scan(value.Interface()); err != nil {
+			return s.wrapErr(err)
+		}
+		slice.Set(reflect.Append(slice, value.Elem()))
+	}
+	if err = rows.Err(); err != nil {
+		return s.wrapErr(err)
+	}
+	if slice.Len() == 0 {
+		return log.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,506 @@
+package session
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/catbugdemo/sorm/clause"
+	"github.com/catbugdemo/sorm/dialect"
+)
+
+type tokenKind int
+
+const (
+	// tokenText is sql copied as it is: keywords, literals, comments, ...
+	tokenText tokenKind = iota
+	// tokenParam is a ? placeholder
+	tokenParam
+	// tokenNative is a placeholder already written in the driver style, such as $1
+	tokenNative
+	// tokenEscaped is ??, written to the driver as a single ?
+	tokenEscaped
+	// tokenNamed is a :name or @name parameter
+	tokenNamed
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// lex splits sql into text and placeholders. ? inside quoted strings,
+// quoted identifiers, comments and dollar-quoted bodies is left alone,
+// ?? is an escaped ? so operators such as jsonb ? can still be written.
+// syntax tells the quotes and comments of the dialect.
+func lex(sql string, syntax dialect.Syntax) ([]token, error) {
+	var tokens []token
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			tokens = append(tokens, token{kind: tokenText, text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end, err := skipQuoted(sql, i, c, backslashEscapes(sql, i, syntax))
+			if err != nil {
+				return nil, err
+			}
+			text.WriteString(sql[i:end])
+			i = end
+		case lineComment(sql, i, syntax):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			text.WriteString(sql[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			text.WriteString(sql[i : i+end+4])
+			i += end + 4
+		case c == '$':
+			if n := digitsAt(sql, i+1); n > 0 {
+				flush()
+				tokens = append(tokens, token{kind: tokenNative, text: sql[i : i+1+n]})
+				i += 1 + n
+				continue
+			}
+			if tag, ok := dollarTag(sql, i); ok {
+				end := strings.Index(sql[i+len(tag):], tag)
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated dollar-quoted string %s at offset %d", tag, i)
+				}
+				text.WriteString(sql[i : i+2*len(tag)+end])
+				i += 2*len(tag) + end
+				continue
+			}
+			text.WriteByte(c)
+			i++
+		case c == '?':
+			flush()
+			if strings.HasPrefix(sql[i:], "??") {
+				tokens = append(tokens, token{kind: tokenEscaped, text: "??"})
+				i += 2
+				continue
+			}
+			tokens = append(tokens, token{kind: tokenParam, text: "?"})
+			i++
+		case (c == ':' || c == '@') && (i == 0 || sql[i-1] != c) && i+1 < len(sql) && isIdentStart(sql[i+1]):
+			// :: casts and @@ system variables are not parameters
+			n := 1
+			for i+n < len(sql) && isIdent(sql[i+n]) {
+				n++
+			}
+			flush()
+			tokens = append(tokens, token{kind: tokenNamed, text: sql[i : i+n]})
+			i += n
+		default:
+			text.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	return tokens, nil
+}
+
+// SplitStatements splits a script on the semicolons ending its statements,
+// semicolons inside quotes, comments and dollar-quoted bodies do not split
+// and statements holding only comments are dropped. The script is read as
+// standard SQL, see SplitStatementsSyntax.
+func SplitStatements(script string) ([]string, error) {
+	return SplitStatementsSyntax(script, dialect.Syntax{})
+}
+
+// SplitStatementsSyntax splits script like SplitStatements, its quotes and
+// comments written in syntax, such as the one of Session.Dialect
+func SplitStatementsSyntax(script string, syntax dialect.Syntax) ([]string, error) {
+	var statements []string
+	var code bool
+	start := 0
+	split := func(end int) {
+		if code {
+			statements = append(statements, strings.TrimSpace(script[start:end]))
+		}
+		start, code = end+1, false
+	}
+
+	for i := 0; i < len(script); {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end, err := skipQuoted(script, i, c, backslashEscapes(script, i, syntax))
+			if err != nil {
+				return nil, err
+			}
+			i, code = end, true
+		case lineComment(script, i, syntax):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			i += end + 4
+		case c == '$':
+			code = true
+			if tag, ok := dollarTag(script, i); ok {
+				end := strings.Index(script[i+len(tag):], tag)
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated dollar-quoted string %s at offset %d", tag, i)
+				}
+				i += 2*len(tag) + end
+				continue
+			}
+			i++
+		case c == ';':
+			split(i)
+			i++
+		default:
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				code = true
+			}
+			i++
+		}
+	}
+	split(len(script))
+	return statements, nil
+}
+
+// backslashEscapes reports whether a backslash escapes the next character
+// of the quoted section at start: every string of the dialects with
+// backslash escapes, E'...' strings otherwise
+func backslashEscapes(sql string, start int, syntax dialect.Syntax) bool {
+	switch sql[start] {
+	case '\'':
+		return syntax.BackslashEscapes || escapeString(sql, start)
+	case '"':
+		return syntax.BackslashEscapes
+	}
+	return false
+}
+
+// lineComment reports whether a comment running to the end of the line
+// starts at start, -- or # when the dialect has hash comments
+func lineComment(sql string, start int, syntax dialect.Syntax) bool {
+	return strings.HasPrefix(sql[start:], "--") || syntax.HashComments && sql[start] == '#'
+}
+
+// escapeString reports whether the string literal at start is an E'...'
+// literal, the only one where backslash escapes a quote
+func escapeString(sql string, start int) bool {
+	if start == 0 || sql[start-1] != 'E' && sql[start-1] != 'e' {
+		return false
+	}
+	if start == 1 {
+		return true
+	}
+	c := sql[start-2]
+	return !isIdent(c)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdent(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
+
+// skipQuoted returns the offset right after the quoted section starting at
+// start, a doubled quote is an escaped quote
+func skipQuoted(sql string, start int, quote byte, backslash bool) (int, error) {
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if backslash {
+				i++
+			}
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated quoted string at offset %d", start)
+}
+
+// dollarTag reports the $tag$ opening a dollar-quoted string at start
+func dollarTag(sql string, start int) (string, bool) {
+	for i := start + 1; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '$':
+			return sql[start : i+1], true
+		case isIdentStart(c) || isIdent(c) && i > start+1:
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+func digitsAt(sql string, start int) int {
+	n := 0
+	for start+n < len(sql) && sql[start+n] >= '0' && sql[start+n] <= '9' {
+		n++
+	}
+	return n
+}
+
+// expandable reports whether value is a list bound as one placeholder per
+// element, []byte and driver.Valuer types are single values
+func expandable(value interface{}) (reflect.Value, bool) {
+	if value == nil {
+		return reflect.Value{}, false
+	}
+	if _, ok := value.(driver.Valuer); ok {
+		return reflect.Value{}, false
+	}
+	if _, ok := value.([]byte); ok {
+		return reflect.Value{}, false
+	}
+	reflectValue := reflect.Indirect(reflect.ValueOf(value))
+	switch reflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		if reflectValue.Type().Elem().Kind() == reflect.Uint8 {
+			return reflect.Value{}, false
+		}
+		return reflectValue, true
+	}
+	return reflect.Value{}, false
+}
+
+// countParams counts the ? and the native placeholders of tokens
+func countParams(tokens []token) (params, natives int) {
+	for _, t := range tokens {
+		switch t.kind {
+		case tokenParam:
+			params++
+		case tokenNative:
+			natives++
+		}
+	}
+	return params, natives
+}
+
+// BindVars expands slice arguments into one placeholder per element and
+// renders ? in the given style, so the session never needs to know which
+// driver it talks to. A clause.Expression argument, such as a *Session, is
+// a subquery rendered in parentheses, its arguments bound in place. The
+// third result is the statement with arguments interpolated for logging.
+// The statement is read as standard SQL, see BindVarsSyntax.
+func BindVars(sql string, vars []interface{}, style dialect.BindVarStyle) (string, []interface{}, string, error) {
+	return BindVarsSyntax(sql, vars, style, dialect.Syntax{})
+}
+
+// BindVarsSyntax binds vars like BindVars, the quotes and comments of sql
+// written in syntax, such as backslash escapes and # comments of mysql
+func BindVarsSyntax(sql string, vars []interface{}, style dialect.BindVarStyle, syntax dialect.Syntax) (string, []interface{}, string, error) {
+	tokens, err := lex(sql, syntax)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	params, natives := countParams(tokens)
+	// statements written with native placeholders are passed through
+	if params == 0 && natives > 0 {
+		return sql, vars, sql, nil
+	}
+	if params != len(vars) {
+		return "", nil, "", fmt.Errorf("expected %d arguments, got %d", params, len(vars))
+	}
+
+	b := &binder{style: style, syntax: syntax, bound: make([]interface{}, 0, len(vars))}
+	if err = b.write(tokens, vars); err != nil {
+		return "", nil, "", err
+	}
+	return b.out.String(), b.bound, b.logs.String(), nil
+}
+
+// binder renders the tokens of a statement and of its subqueries, numbering
+// the placeholders of both in order
+type binder struct {
+	style  dialect.BindVarStyle
+	syntax dialect.Syntax
+	out    strings.Builder
+	logs   strings.Builder
+	bound  []interface{}
+}
+
+func (b *binder) bind(value interface{}) {
+	b.bound = append(b.bound, value)
+	b.out.WriteString(b.style.Placeholder(len(b.bound)))
+	b.logs.WriteString(fmt.Sprintf("'%v'", value))
+}
+
+func (b *binder) text(text string) {
+	b.out.WriteString(text)
+	b.logs.WriteString(text)
+}
+
+func (b *binder) write(tokens []token, vars []interface{}) error {
+	var index int
+	for _, t := range tokens {
+		switch t.kind {
+		case tokenParam:
+		case tokenEscaped:
+			b.text("?")
+			continue
+		default:
+			b.text(t.text)
+			continue
+		}
+		value := vars[index]
+		index++
+		if err, ok := value.(*bindError); ok {
+			return err
+		}
+		if expr, ok := value.(clause.Expression); ok {
+			if err := b.subquery(expr); err != nil {
+				return err
+			}
+			continue
+		}
+		list, ok := expandable(value)
+		if !ok {
+			b.bind(value)
+			continue
+		}
+		if list.Len() == 0 {
+			// IN (NULL) matches nothing, IN () is a syntax error
+			b.text("NULL")
+			continue
+		}
+		for j := 0; j < list.Len(); j++ {
+			if j > 0 {
+				b.text(",")
+			}
+			b.bind(list.Index(j).Interface())
+		}
+	}
+	return nil
+}
+
+// subquery renders expr in parentheses, its placeholders numbered after the
+// ones already bound
+func (b *binder) subquery(expr clause.Expression) error {
+	sql, vars := expr.Build()
+	tokens, err := lex(strings.TrimSpace(sql), b.syntax)
+	if err != nil {
+		return err
+	}
+	params, natives := countParams(tokens)
+	if natives > 0 {
+		return fmt.Errorf("subquery %q must use ? placeholders", sql)
+	}
+	if params != len(vars) {
+		return fmt.Errorf("subquery %q expected %d arguments, got %d", sql, params, len(vars))
+	}
+	b.text("(")
+	if err = b.write(tokens, vars); err != nil {
+		return err
+	}
+	b.text(")")
+	return nil
+}
+
+// bindError is bound in place of a value that could not be resolved,
+// BindVars reports it once the statement is executed
+type bindError struct {
+	msg string
+}
+
+func (e *bindError) Error() string {
+	return e.msg
+}
+
+// namedArgs returns the values of a single map or struct argument keyed by
+// parameter name, structs are keyed by the same db columns as schema.Parse
+func (s *Session) namedArgs(values []interface{}) (map[string]interface{}, bool) {
+	if len(values) != 1 || values[0] == nil {
+		return nil, false
+	}
+	if _, ok := values[0].(driver.Valuer); ok {
+		return nil, false
+	}
+	value := reflect.Indirect(reflect.ValueOf(values[0]))
+	switch value.Kind() {
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		m := make(map[string]interface{}, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		return m, true
+	case reflect.Struct:
+		if _, ok := value.Interface().(time.Time); ok {
+			return nil, false
+		}
+		table := s.parse(value.Interface())
+		m := make(map[string]interface{}, len(table.Fields))
+		for _, field := range table.Fields {
+			var v interface{}
+			if fieldValue := field.ValueOf(value); fieldValue.IsValid() {
+				v = fieldValue.Interface()
+			}
+			m[field.Name] = v
+			m[field.SqlName] = v
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+// bindNamed rewrites :name and @name parameters to ? when values is a single
+// map or struct, the statement is returned untouched otherwise
+func (s *Session) bindNamed(sql string, values []interface{}) (string, []interface{}) {
+	m, ok := s.namedArgs(values)
+	if !ok {
+		return sql, values
+	}
+	tokens, err := lex(sql, s.dialect.Syntax())
+	if err != nil {
+		return sql, values
+	}
+
+	var out strings.Builder
+	var vars []interface{}
+	var named bool
+	for _, t := range tokens {
+		if t.kind != tokenNamed {
+			out.WriteString(t.text)
+			continue
+		}
+		named = true
+		out.WriteString("?")
+		if v, ok := m[t.text[1:]]; ok {
+			vars = append(vars, v)
+		} else {
+			vars = append(vars, &bindError{msg: fmt.Sprintf("named parameter %s has no value", t.text)})
+		}
+	}
+	if !named {
+		return sql, values
+	}
+	return out.String(), vars
+}
@@ -0,0 +1,180 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/catbugdemo/sorm/clause"
+	"github.com/catbugdemo/sorm/dialect"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindVars(t *testing.T) {
+	sql, vars, _, err := BindVars("SELECT * FROM user_test WHERE age > ? AND id IN (?)", []interface{}{18, []int{1, 2}}, dialect.Question)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM user_test WHERE age > ? AND id IN (?,?)", sql)
+	assert.Equal(t, []interface{}{18, 1, 2}, vars)
+
+	sql, vars, _, err = BindVars("SELECT * FROM user_test WHERE age > ? AND id IN (?)", []interface{}{18, []int{1, 2}}, dialect.Dollar)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM user_test WHERE age > $1 AND id IN ($2,$3)", sql)
+	assert.Equal(t, []interface{}{18, 1, 2}, vars)
+
+	sub := clause.Expr{SQL: "SELECT user_id FROM orders WHERE amount > ? AND state IN (?)", Vars: []interface{}{100, []string{"a", "b"}}}
+	sql, vars, _, err = BindVars("SELECT * FROM user_test WHERE id IN ? AND age > ?", []interface{}{sub, 18}, dialect.Dollar)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM user_test WHERE id IN (SELECT user_id FROM orders WHERE amount > $1 AND state IN ($2,$3)) AND age > $4", sql)
+	assert.Equal(t, []interface{}{100, "a", "b", 18}, vars)
+
+	_, _, _, err = BindVars("SELECT * FROM user_test WHERE id IN ?", []interface{}{clause.Expr{SQL: "SELECT id FROM t WHERE a = $1", Vars: []interface{}{1}}}, dialect.Dollar)
+	assert.NotNil(t, err)
+}
+
+func TestBindVarsStyle(t *testing.T) {
+	query := "UPDATE user_test SET name=? WHERE id=?"
+	for style, want := range map[dialect.BindVarStyle]string{
+		dialect.Question: "UPDATE user_test SET name=? WHERE id=?",
+		dialect.Dollar:   "UPDATE user_test SET name=$1 WHERE id=$2",
+		dialect.Colon:    "UPDATE user_test SET name=:1 WHERE id=:2",
+		dialect.AtP:      "UPDATE user_test SET name=@p1 WHERE id=@p2",
+	} {
+		sql, _, _, err := BindVars(query, []interface{}{"Tom", 1}, style)
+		assert.Nil(t, err)
+		assert.Equal(t, want, sql)
+	}
+}
+
+func TestBindVarsLexer(t *testing.T) {
+	cases := []struct {
+		sql  string
+		vars []interface{}
+		want string
+		args []interface{}
+	}{
+		{
+			sql:  "SELECT * FROM t WHERE name = 'who?' AND id = ?",
+			vars: []interface{}{1},
+			want: "SELECT * FROM t WHERE name = 'who?' AND id = $1",
+			args: []interface{}{1},
+		},
+		{
+			sql:  "SELECT * FROM t WHERE note = 'it''s in (?)' AND id not in (?) AND age in (?)",
+			vars: []interface{}{[]int{1, 2}, []int{3}},
+			want: "SELECT * FROM t WHERE note = 'it''s in (?)' AND id not in ($1,$2) AND age in ($3)",
+			args: []interface{}{1, 2, 3},
+		},
+		{
+			sql:  `SELECT "what?" FROM t -- why?` + "\n" + `WHERE /* ? */ data ?? 'key' AND id = ?`,
+			vars: []interface{}{1},
+			want: `SELECT "what?" FROM t -- why?` + "\n" + `WHERE /* ? */ data ? 'key' AND id = $1`,
+			args: []interface{}{1},
+		},
+		{
+			sql:  "SELECT $body$ ? $body$, E'\\'?' FROM t WHERE id = ANY(?) AND blob = ?",
+			vars: []interface{}{[]string{"a"}, []byte("b")},
+			want: "SELECT $body$ ? $body$, E'\\'?' FROM t WHERE id = ANY($1) AND blob = $2",
+			args: []interface{}{"a", []byte("b")},
+		},
+		{
+			sql:  "SELECT * FROM t WHERE id IN (?)",
+			vars: []interface{}{[]int{}},
+			want: "SELECT * FROM t WHERE id IN (NULL)",
+			args: []interface{}{},
+		},
+		{
+			sql:  "SELECT * FROM t WHERE id = $1",
+			vars: []interface{}{1},
+			want: "SELECT * FROM t WHERE id = $1",
+			args: []interface{}{1},
+		},
+	}
+	for _, c := range cases {
+		sql, args, _, err := BindVars(c.sql, c.vars, dialect.Dollar)
+		assert.Nil(t, err)
+		assert.Equal(t, c.want, sql)
+		assert.Equal(t, c.args, args)
+	}
+}
+
+func TestBindVarsSyntax(t *testing.T) {
+	mysql, _ := dialect.GetDialect("mysql")
+	sql, args, _, err := BindVarsSyntax("SELECT * FROM t WHERE note = 'it\\'s ?' AND title = \"\\\"?\" # why?\nAND id = ?", []interface{}{1}, dialect.Question, mysql.Syntax())
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE note = 'it\\'s ?' AND title = \"\\\"?\" # why?\nAND id = ?", sql)
+	assert.Equal(t, []interface{}{1}, args)
+
+	// the backslash does not escape the quote in standard SQL
+	_, _, _, err = BindVars("SELECT * FROM t WHERE note = 'it\\'s ?' AND id = ?", []interface{}{1}, dialect.Question)
+	assert.NotNil(t, err)
+
+	sql, args, err = New(nil, mysql).ToSQL(func(tx *Session) error {
+		return tx.Table("t").Where("note <> 'a\\'?' # b?\n").Where("id = ?", 1).Find(&[]namedUser{})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT name,age FROM t WHERE note <> 'a\\'?' # b?\n AND id = ?", sql)
+	assert.Equal(t, []interface{}{1}, args)
+
+	statements, err := SplitStatementsSyntax("# users; first\nINSERT INTO users VALUES ('a\\';b');\nDELETE FROM users", mysql.Syntax())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"# users; first\nINSERT INTO users VALUES ('a\\';b')", "DELETE FROM users"}, statements)
+}
+
+func TestBindVarsError(t *testing.T) {
+	_, _, _, err := BindVars("SELECT * FROM t WHERE id = ? AND name = ?", []interface{}{1}, dialect.Dollar)
+	assert.NotNil(t, err)
+
+	_, _, _, err = BindVars("SELECT * FROM t WHERE id = ?", []interface{}{1, 2}, dialect.Dollar)
+	assert.NotNil(t, err)
+
+	_, _, _, err = BindVars("SELECT * FROM t WHERE name = 'Tom", nil, dialect.Dollar)
+	assert.NotNil(t, err)
+}
+
+type namedUser struct {
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+}
+
+func TestBindNamed(t *testing.T) {
+	dial, _ := dialect.GetDialect("postgres")
+
+	s := New(nil, dial).Raw("SELECT * FROM t WHERE name = :name AND age > @age AND ids IN (:ids) AND at::date = '10:00'", map[string]interface{}{
+		"name": "Tom",
+		"age":  18,
+		"ids":  []int{1, 2},
+	})
+	sql, vars, _, err := BindVars(s.sql.String(), s.sqlVars, dial.BindVarStyle())
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE name = $1 AND age > $2 AND ids IN ($3,$4) AND at::date = '10:00' ", sql)
+	assert.Equal(t, []interface{}{"Tom", 18, 1, 2}, vars)
+
+	s = New(nil, dial).Where("name = :name", &namedUser{Name: "Tom"}).Where("age > :Age OR age < :age", namedUser{Age: 18})
+	sql, vars = s.clause.Get(clause.WHERE)
+	assert.Equal(t, "WHERE name = ? AND (age > ? OR age < ?)", sql)
+	assert.Equal(t, []interface{}{"Tom", 18, 18}, vars)
+
+	s = New(nil, dial).Raw("SELECT * FROM t WHERE name = :nickname", namedUser{})
+	_, _, _, err = BindVars(s.sql.String(), s.sqlVars, dial.BindVarStyle())
+	assert.NotNil(t, err)
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements, err := SplitStatements(`
+-- users; first
+CREATE TABLE users (id int, name text DEFAULT 'a;b');
+/* ; */
+CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;
+INSERT INTO users VALUES (1, E'\';')`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"-- users; first\nCREATE TABLE users (id int, name text DEFAULT 'a;b')",
+		"/* ; */\nCREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql",
+		`INSERT INTO users VALUES (1, E'\';')`,
+	}, statements)
+
+	statements, err = SplitStatements("-- nothing;\n;")
+	assert.Nil(t, err)
+	assert.Empty(t, statements)
+
+	_, err = SplitStatements("SELECT 'a")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,107 @@
+package session
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/catbugdemo/sorm/clause"
+)
+
+// cte is a common table expression of With and WithRecursive
+type cte struct {
+	// name may list the columns, such as "tree(id, parent_id)"
+	name      string
+	query     clause.Expression
+	recursive bool
+}
+
+// With adds the common table expression name to the statement, query is a
+// subquery such as a *Session, or SQL with ? placeholders and args:
+//
+//	s.With("adults", s2.Model(&User{}).Where("age >= ?", 18)).Table("adults").Find(&users)
+func (s *Session) With(name string, query interface{}, args ...interface{}) *Session {
+	return s.addCTE(cte{name: name, query: s.cteQuery(query, args)})
+}
+
+// WithRecursive adds a common table expression referring to itself, written
+// as an anchor query and a recursive one joined with UNION:
+//
+//	s.WithRecursive("tree",
+//		"SELECT id, parent_id FROM category WHERE id = ? UNION ALL "+
+//			"SELECT c.id, c.parent_id FROM category c INNER JOIN tree ON c.parent_id = tree.id", 1).
+//		Table("tree").Find(&categories)
+func (s *Session) WithRecursive(name string, query interface{}, args ...interface{}) *Session {
+	return s.addCTE(cte{name: name, query: s.cteQuery(query, args), recursive: true})
+}
+
+func (s *Session) cteQuery(query interface{}, args []interface{}) clause.Expression {
+	switch q := query.(type) {
+	case clause.Expression:
+		return q
+	case string:
+		sql, vars := s.bindNamed(q, args)
+		return clause.Expr{SQL: sql, Vars: vars}
+	}
+	return clause.Expr{SQL: "?", Vars: []interface{}{&bindError{msg: fmt.Sprintf("unsupported common table expression %T", query)}}}
+}
+
+// addCTE sets the WITH clause, RECURSIVE once any of the expressions is
+func (s *Session) addCTE(c cte) *Session {
+	s.ctes = append(s.ctes, c)
+	var recursive bool
+	names := make([]string, 0, len(s.ctes))
+	vars := make([]interface{}, 0, len(s.ctes)+1)
+	vars = append(vars, "")
+	for _, c := range s.ctes {
+		recursive = recursive || c.recursive
+		// the query is bound as a subquery, rendered in parentheses
+		names = append(names, c.name+" AS ?")
+		vars = append(vars, c.query)
+	}
+	vars[0] = strings.Join(names, ", ")
+	if recursive {
+		vars[0] = "RECURSIVE " + vars[0].(string)
+	}
+	s.clause.Set(clause.WITH, vars...)
+	return s
+}
+
+// Descendants finds the rows of the tree rooted at root into dest, a pointer
+// to a slice of models whose parentColumn holds the primary key of their
+// parent. root is the primary key of the root row, nil starts from every row
+// without parent. Where and OrderBy apply to the rows found. Rows are found
+// once even if the tree has a cycle.
+//
+//	var categories []Category
+//	s.OrderBy("name").Descendants(&categories, "parent_id", 1)
+func (s *Session) Descendants(dest interface{}, parentColumn string, root interface{}) error {
+	destType := reflect.Indirect(reflect.ValueOf(dest)).Type().Elem()
+	table, err := s.modelTable(reflect.New(destType).Elem().Interface())
+	if err != nil {
+		return err
+	}
+	primary := table.PrimaryField()
+	if primary == nil {
+		return fmt.Errorf("model %s has no primary key", table.Name)
+	}
+
+	const tree = "sorm_tree"
+	anchor := fmt.Sprintf("%s IS NULL", parentColumn)
+	var vars []interface{}
+	if root != nil {
+		anchor = fmt.Sprintf("%s = ?", primary.SqlName)
+		vars = append(vars, root)
+	}
+	columns := make([]string, 0, len(table.FieldNames))
+	for _, name := range table.FieldNames {
+		columns = append(columns, "t."+name)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s t WHERE %s UNION SELECT %s FROM %s t INNER JOIN %s ON t.%s = %s.%s",
+		strings.Join(columns, ","), table.SqlName, anchor,
+		strings.Join(columns, ","), table.SqlName, tree, parentColumn, tree, primary.SqlName)
+	// the tree is the table of this statement only, unlike Table
+	s.WithRecursive(fmt.Sprintf("%s(%s)", tree, strings.Join(table.FieldNames, ",")), query, vars...)
+	s.clause.Set(clause.TABLE, tree)
+	return s.Find(dest)
+}
@@ -0,0 +1,84 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/catbugdemo/sorm/log"
+)
+
+// Statement is a statement as the driver receives it, placeholders already
+// rendered in the style of the dialect
+type Statement struct {
+	SQL  string
+	Vars []interface{}
+}
+
+// DryRun builds the statements of the session without executing them,
+// Exec, QueryRow and QueryRows record the statement and return
+// log.ErrDryRun, so Find, Insert, Update, ... stop before touching the database
+func (s *Session) DryRun() *Session {
+	s.dryRun = true
+	return s
+}
+
+// Statements returns the statements recorded in dry run mode
+func (s *Session) Statements() []Statement {
+	return s.statements
+}
+
+func (s *Session) record(sql string, vars []interface{}) error {
+	s.statements = append(s.statements, Statement{SQL: strings.TrimSpace(sql), Vars: vars})
+	return log.ErrDryRun
+}
+
+// ToSQL runs f on a dry run copy of the session and returns the statement it
+// would execute first, the session itself is left unchanged
+//
+//	sql, vars, err := s.ToSQL(func(tx *Session) error {
+//		return tx.Where("age > ?", 18).Find(&users)
+//	})
+func (s *Session) ToSQL(f func(tx *Session) error) (string, []interface{}, error) {
+	dry := s.clone().DryRun()
+	if err := f(dry); err != nil && !errors.Is(err, log.ErrDryRun) {
+		return "", nil, err
+	}
+	if len(dry.statements) == 0 {
+		return "", nil, fmt.Errorf("no statement executed")
+	}
+	statement := dry.statements[0]
+	return statement.SQL, statement.Vars, nil
+}
+
+// clone copies the session with its pending statement and clauses
+func (s *Session) clone() *Session {
+	clone := &Session{
+		db:         s.db,
+		dialect:    s.dialect,
+		tx:         s.tx,
+		refTable:   s.refTable,
+		clause:     s.clause.Clone(),
+		sqlVars:    append([]interface{}(nil), s.sqlVars...),
+		where:      s.where,
+		having:     s.having,
+		selects:    s.selects,
+		selectVars: s.selectVars,
+		omits:      s.omits,
+		distinct:   s.distinct,
+		joins:      append([]join(nil), s.joins...),
+		ctes:       append([]cte(nil), s.ctes...),
+		content:    s.content,
+		ctx:        s.ctx,
+		namer:      s.namer,
+		dryRun:     s.dryRun,
+		err:        s.err,
+	}
+	if s.conflict != nil {
+		conflict := *s.conflict
+		conflict.session = clone
+		clone.conflict = &conflict
+	}
+	clone.sql.WriteString(s.sql.String())
+	return clone
+}
@@ -0,0 +1,132 @@
+package session
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/catbugdemo/sorm/clause"
+	"github.com/catbugdemo/sorm/schema"
+)
+
+// join is a table joined by Joins, InnerJoin or LeftJoin
+type join struct {
+	expr clause.Expr
+	// table is the name or alias qualifying the columns of the joined table
+	table string
+	// schema is the model of InnerJoin and LeftJoin, nil for Joins
+	schema *schema.Schema
+}
+
+// Joins adds a join written in SQL, such as
+// Joins("LEFT JOIN company c ON c.id = user.company_id AND c.active = ?", true)
+func (s *Session) Joins(query string, args ...interface{}) *Session {
+	query, args = s.bindNamed(query, args)
+	return s.addJoin(join{expr: clause.Expr{SQL: query, Vars: args}})
+}
+
+// InnerJoin joins the table of model on the condition on
+func (s *Session) InnerJoin(model interface{}, on string, args ...interface{}) *Session {
+	return s.modelJoin("INNER JOIN", model, on, args)
+}
+
+// LeftJoin left joins the table of model on the condition on
+func (s *Session) LeftJoin(model interface{}, on string, args ...interface{}) *Session {
+	return s.modelJoin("LEFT JOIN", model, on, args)
+}
+
+// modelJoin joins the table of model, a struct or a table name, the fields
+// of the Find destination embedding model are read from the joined table
+func (s *Session) modelJoin(kind string, model interface{}, on string, args []interface{}) *Session {
+	var j join
+	table, ok := model.(string)
+	if ok {
+		j.table = qualifier(table)
+	} else {
+		j.schema = s.parse(model)
+		table, j.table = j.schema.SqlName, j.schema.SqlName
+	}
+	on, args = s.bindNamed(on, args)
+	j.expr = clause.Expr{SQL: fmt.Sprintf("%s %s ON %s", kind, table, on), Vars: args}
+	return s.addJoin(j)
+}
+
+func (s *Session) addJoin(j join) *Session {
+	s.joins = append(s.joins, j)
+	exprs := make([]interface{}, 0, len(s.joins))
+	for _, j := range s.joins {
+		exprs = append(exprs, j.expr)
+	}
+	s.clause.Set(clause.JOIN, exprs...)
+	return s
+}
+
+// qualifier returns the name qualifying the columns of a table clause,
+// the alias of "user u" or "user AS u", the table of "user"
+func qualifier(table string) string {
+	words := strings.Fields(table)
+	if len(words) == 0 {
+		return table
+	}
+	return words[len(words)-1]
+}
+
+// joinSelect returns the columns Find selects once tables are joined, the
+// columns of table are qualified by the table of the statement, the fields of
+// a struct embedded with `sorm:"embedded;prefix:..."` whose type is a joined
+// model are read from the joined table under their prefixed name:
+//
+//	company.name AS company_name
+func (s *Session) joinSelect(table *schema.Schema, from string) []string {
+	modelType := reflect.Indirect(reflect.ValueOf(table.Model)).Type()
+	columns := make([]string, 0, len(table.Fields))
+	for _, field := range table.Fields {
+		if column, ok := s.joinedColumn(modelType, field); ok {
+			columns = append(columns, column)
+			continue
+		}
+		columns = append(columns, qualifier(from)+"."+field.SqlName)
+	}
+	return columns
+}
+
+// joinedColumn returns the column of field read from a joined model
+func (s *Session) joinedColumn(modelType reflect.Type, field *schema.Field) (string, bool) {
+	if len(field.Index) < 2 {
+		return "", false
+	}
+	embedded := modelType.Field(field.Index[0])
+	if embedded.Anonymous {
+		return "", false
+	}
+	for _, j := range s.joins {
+		if j.schema == nil || indirect(embedded.Type) != reflect.Indirect(reflect.ValueOf(j.schema.Model)).Type() {
+			continue
+		}
+		for _, joined := range j.schema.Fields {
+			if equalIndex(joined.Index, field.Index[1:]) {
+				return fmt.Sprintf("%s.%s AS %s", j.table, joined.SqlName, field.SqlName), true
+			}
+		}
+	}
+	return "", false
+}
+
+func indirect(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+func equalIndex(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,178 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/catbugdemo/sorm/dialect"
+	"github.com/catbugdemo/sorm/log"
+)
+
+// lockRetryInterval is the wait between two attempts to take a lock
+var lockRetryInterval = 100 * time.Millisecond
+
+// StaleLockAge is how long a row of the sorm_locks table lives without being
+// refreshed before another process takes it over, its holder refreshes it
+// while alive so only the lock of a process that died is ever taken over. 0
+// never takes a lock over, it is then released by deleting its row by hand.
+var StaleLockAge = time.Minute
+
+// lockRefreshes is how many times a table lock is refreshed within StaleLockAge
+const lockRefreshes = 4
+
+// Lock takes the database lock name, waiting up to timeout for its holder to
+// release it, a timeout of 0 waits until the session context is done. The
+// lock is taken outside the transaction of the session, the dialect decides
+// how: an advisory lock held by a dedicated connection on postgres, a row of
+// the sorm_locks table on sqlite and mysql, see StaleLockAge. unlock
+// releases it.
+func (s *Session) Lock(name string, timeout time.Duration) (unlock func() error, err error) {
+	lock := s.dialect.Lock(name)
+	style, syntax := s.dialect.BindVarStyle(), s.dialect.Syntax()
+	acquire, args, _, err := BindVarsSyntax(lock.AcquireSQL, lock.Args, style, syntax)
+	if err != nil {
+		return nil, err
+	}
+	release, _, _, err := BindVarsSyntax(lock.ReleaseSQL, lock.Args, style, syntax)
+	if err != nil {
+		return nil, err
+	}
+	var refresh, stale string
+	var staleArgs []interface{}
+	staleAge := int64(StaleLockAge / time.Second)
+	if lock.Mode == dialect.TableLock && staleAge > 0 {
+		if refresh, _, _, err = BindVarsSyntax(lock.RefreshSQL, lock.Args, style, syntax); err != nil {
+			return nil, err
+		}
+		staleArgs = append(append([]interface{}{}, lock.Args...), staleAge)
+		if stale, staleArgs, _, err = BindVarsSyntax(lock.StaleSQL, staleArgs, style, syntax); err != nil {
+			return nil, err
+		}
+	}
+
+	var db CommonDB = s.db
+	var conn *sql.Conn
+	closeConn := func() {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}
+	switch lock.Mode {
+	case dialect.AdvisoryLock:
+		// advisory locks belong to the connection that took them
+		if conn, err = s.db.Conn(s.Context()); err != nil {
+			return nil, s.wrapErr(err)
+		}
+		db = conn
+	case dialect.TableLock:
+		if _, err = s.db.ExecContext(s.Context(), lock.CreateSQL); err != nil {
+			return nil, s.wrapErr(err)
+		}
+	}
+
+	ctx := s.Context()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	for {
+		if stale != "" {
+			if err = takeOverStale(ctx, db, name, stale, staleArgs); err != nil {
+				closeConn()
+				log.Error(err)
+				return nil, s.wrapErr(err)
+			}
+		}
+		ok, err := tryLock(ctx, db, lock.Mode, acquire, args)
+		if err == nil && ok {
+			break
+		}
+		if err == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(lockRetryInterval):
+				continue
+			}
+		}
+		closeConn()
+		switch {
+		case s.Context().Err() != nil:
+			err = s.wrapErr(s.Context().Err())
+		case ctx.Err() != nil:
+			err = fmt.Errorf("%w: %s not released after %s", log.ErrLockTimeout, name, timeout)
+		}
+		log.Error(err)
+		return nil, err
+	}
+	log.Infof("lock %s acquired", name)
+
+	stop, refreshed := make(chan struct{}), make(chan struct{})
+	if refresh != "" {
+		go refreshLock(db, name, refresh, args, StaleLockAge/lockRefreshes, stop, refreshed)
+	} else {
+		close(refreshed)
+	}
+	var stopOnce sync.Once
+	return func() error {
+		defer closeConn()
+		// no refresh may run once the lock is released and taken by another
+		stopOnce.Do(func() { close(stop) })
+		<-refreshed
+		// released even when the session context is done
+		if _, err := db.ExecContext(context.Background(), release, args...); err != nil {
+			log.Error(err)
+			return err
+		}
+		log.Infof("lock %s released", name)
+		return nil
+	}, nil
+}
+
+// takeOverStale deletes the lock name if its holder stopped refreshing it
+func takeOverStale(ctx context.Context, db CommonDB, name, query string, args []interface{}) error {
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		log.Infof("stale lock %s taken over", name)
+	}
+	return nil
+}
+
+// refreshLock keeps a table lock from going stale every interval until stop
+// is closed, then closes done
+func refreshLock(db CommonDB, name, query string, args []interface{}, interval time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := db.ExecContext(context.Background(), query, args...); err != nil {
+				log.Errorf("refresh lock %s: %v", name, err)
+			}
+		}
+	}
+}
+
+// tryLock makes one attempt to take a lock without waiting
+func tryLock(ctx context.Context, db CommonDB, mode dialect.LockMode, query string, args []interface{}) (bool, error) {
+	if mode == dialect.AdvisoryLock {
+		var ok bool
+		err := db.QueryRowContext(ctx, query, args...).Scan(&ok)
+		return ok, err
+	}
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
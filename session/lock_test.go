@@ -0,0 +1,74 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/catbugdemo/sorm/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	s := sqliteSession(t)
+	retry := lockRetryInterval
+	lockRetryInterval = 10 * time.Millisecond
+	defer func() { lockRetryInterval = retry }()
+
+	unlock, err := s.Lock("job", time.Second)
+	assert.Nil(t, err)
+	var n int
+	assert.Nil(t, s.Raw("SELECT count(*) FROM sorm_locks WHERE name = ?", "job").QueryRow().Scan(&n))
+	assert.Equal(t, 1, n)
+
+	// other names are not held
+	other, err := s.Lock("other", time.Second)
+	assert.Nil(t, err)
+	assert.Nil(t, other())
+
+	// the held lock times out
+	_, err = s.Lock("job", 50*time.Millisecond)
+	assert.True(t, errors.Is(err, log.ErrLockTimeout))
+
+	// a waiter gets the lock once released
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		assert.Nil(t, unlock())
+	}()
+	unlock, err = s.Lock("job", time.Second)
+	assert.Nil(t, err)
+	assert.Nil(t, unlock())
+	assert.Nil(t, s.Raw("SELECT count(*) FROM sorm_locks").QueryRow().Scan(&n))
+	assert.Equal(t, 0, n)
+}
+
+func TestLockStale(t *testing.T) {
+	s := sqliteSession(t)
+	retry, age := lockRetryInterval, StaleLockAge
+	lockRetryInterval, StaleLockAge = 10*time.Millisecond, 2*time.Second
+	defer func() { lockRetryInterval, StaleLockAge = retry, age }()
+
+	// the lock of a dead process is taken over
+	unlock, err := s.Lock("job", time.Second)
+	assert.Nil(t, err)
+	assert.Nil(t, unlock())
+	_, err = s.Raw("INSERT INTO sorm_locks (name, locked_at) VALUES (?, ?)", "job", "2000-01-01 00:00:00").Exec()
+	assert.Nil(t, err)
+	unlock, err = s.Lock("job", 50*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Nil(t, unlock())
+
+	// a live holder refreshes its lock past StaleLockAge
+	unlock, err = s.Lock("job", time.Second)
+	assert.Nil(t, err)
+	_, err = s.Lock("job", 3*time.Second)
+	assert.True(t, errors.Is(err, log.ErrLockTimeout))
+	assert.Nil(t, unlock())
+
+	// never taken over without StaleLockAge
+	StaleLockAge = 0
+	_, err = s.Raw("INSERT INTO sorm_locks (name, locked_at) VALUES (?, ?)", "job", "2000-01-01 00:00:00").Exec()
+	assert.Nil(t, err)
+	_, err = s.Lock("job", 50*time.Millisecond)
+	assert.True(t, errors.Is(err, log.ErrLockTimeout))
+}
@@ -0,0 +1,307 @@
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/catbugdemo/sorm/dialect"
+	"github.com/catbugdemo/sorm/schema"
+)
+
+// MigrateOptions controls the changes AutoMigrate may apply on top of adding
+// tables, columns and indexes, the zero value never alters nor drops anything
+type MigrateOptions struct {
+	// WidenTypes alters columns to a type holding every current value,
+	// such as int to bigint or varchar(64) to varchar(255)
+	WidenTypes bool
+	// NarrowTypes alters columns to any other type, which may lose data
+	NarrowTypes bool
+	// DropColumns drops the columns that are not in the model
+	DropColumns bool
+}
+
+// MigrationAction is the kind of a MigrationChange
+type MigrationAction string
+
+const (
+	CreateTableAction MigrationAction = "create table"
+	AddColumnAction   MigrationAction = "add column"
+	AlterColumnAction MigrationAction = "alter column"
+	DropColumnAction  MigrationAction = "drop column"
+	CreateIndexAction MigrationAction = "create index"
+	// CreateTriggerAction recreates a trigger dropped with its table by a
+	// RebuildTableAction
+	CreateTriggerAction MigrationAction = "create trigger"
+	// RebuildTableAction copies a table into a new one, it applies the column
+	// changes the dialect cannot alter in place
+	RebuildTableAction MigrationAction = "rebuild table"
+)
+
+// MigrationChange is one difference between a model and its table
+type MigrationChange struct {
+	Table  string
+	Action MigrationAction
+	// Name is the column or index changed, empty for tables
+	Name string
+	// SQL is empty for the changes applied by a RebuildTableAction
+	SQL string
+	// Reason tells why a change was skipped
+	Reason string
+}
+
+// MigrationReport lists the changes AutoMigrate applied and the ones it
+// skipped because MigrateOptions or the dialect did not allow them
+type MigrationReport struct {
+	Applied []MigrationChange
+	Skipped []MigrationChange
+}
+
+// AutoMigrate creates the missing tables, columns and indexes of models,
+// existing columns are never altered nor dropped, see AutoMigrateWith
+func (s *Session) AutoMigrate(models ...interface{}) (*MigrationReport, error) {
+	return s.AutoMigrateWith(MigrateOptions{}, models...)
+}
+
+// AutoMigrateWith migrates the tables of models like AutoMigrate, applying
+// the type changes and drops allowed by opts. The report holds the changes
+// applied before an error.
+func (s *Session) AutoMigrateWith(opts MigrateOptions, models ...interface{}) (*MigrationReport, error) {
+	report := &MigrationReport{}
+	for _, model := range models {
+		if _, err := s.modelTable(model); err != nil {
+			return report, err
+		}
+		if err := s.migrateTable(opts, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (s *Session) migrateTable(opts MigrateOptions, report *MigrationReport) error {
+	table := s.RefTable()
+	if !s.HasTable() {
+		query := createTableSQL(table)
+		if _, err := s.raw(query, nil).Exec(); err != nil {
+			return err
+		}
+		report.Applied = append(report.Applied, MigrationChange{Table: table.SqlName, Action: CreateTableAction, SQL: query})
+		return s.migrateIndexes(report)
+	}
+
+	columns, err := s.columns()
+	if err != nil {
+		return err
+	}
+	// the table a rebuild would produce, in case the dialect cannot alter in place
+	plan := &rebuildPlan{}
+	composite := compositeKey(table)
+	if composite {
+		plan.primaryKey = primaryKeyConstraint(table)
+	}
+	for _, field := range table.Fields {
+		current, ok := columns.get(field.SqlName)
+		if !ok {
+			change := MigrationChange{Table: table.SqlName, Action: AddColumnAction, Name: field.SqlName}
+			if field.PrimaryKey {
+				change.Reason = "primary key columns cannot be added to an existing table"
+				report.Skipped = append(report.Skipped, change)
+				continue
+			}
+			change.SQL = fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table.SqlName, addColumnDefinition(field))
+			if err = s.apply(change, report); err != nil {
+				return err
+			}
+			if field.Unique {
+				if err = s.addUniqueIndex(field, report); err != nil {
+					return err
+				}
+			}
+			plan.keep(field.SqlName, addColumnDefinition(field))
+			continue
+		}
+		current.migrated = true
+
+		typeChange := s.dialect.CompareType(current.dataType, field.Type)
+		if typeChange == dialect.TypeSame {
+			plan.keep(field.SqlName, rebuildDefinition(field, current, composite))
+			continue
+		}
+		change := MigrationChange{Table: table.SqlName, Action: AlterColumnAction, Name: field.SqlName}
+		switch {
+		case typeChange == dialect.TypeWiden && !opts.WidenTypes:
+			change.Reason = fmt.Sprintf("widening %s to %s is not enabled", current.dataType, field.Type)
+		case typeChange == dialect.TypeNarrow && !opts.NarrowTypes:
+			change.Reason = fmt.Sprintf("changing %s to %s may lose data", current.dataType, field.Type)
+		}
+		if change.Reason != "" {
+			report.Skipped = append(report.Skipped, change)
+			plan.keep(field.SqlName, rebuildDefinition(field, current, composite))
+			continue
+		}
+		query, ok := s.dialect.AlterColumnSQL(table.SqlName, field.SqlName, alterColumnDefinition(field), field.Type)
+		if !ok {
+			plan.change(change, field.SqlName, rebuildDefinition(field, &column{dataType: field.Type, notNull: current.notNull, defaultValue: current.defaultValue}, composite))
+			continue
+		}
+		change.SQL = query
+		if err = s.apply(change, report); err != nil {
+			return err
+		}
+		plan.keep(field.SqlName, alterColumnDefinition(field))
+	}
+
+	// the columns left are not in the model
+	for _, current := range columns {
+		if current.migrated {
+			continue
+		}
+		change := MigrationChange{Table: table.SqlName, Action: DropColumnAction, Name: current.name}
+		if !opts.DropColumns {
+			change.Reason = "dropping columns is not enabled"
+			report.Skipped = append(report.Skipped, change)
+			plan.keep(current.name, current.definition())
+			continue
+		}
+		query, ok := s.dialect.DropColumnSQL(table.SqlName, current.name)
+		if !ok {
+			plan.drop(change, current.name)
+			continue
+		}
+		change.SQL = query
+		if err = s.apply(change, report); err != nil {
+			return err
+		}
+	}
+
+	if len(plan.changes) > 0 {
+		if err = s.rebuildTable(plan, report); err != nil {
+			return err
+		}
+	}
+	return s.migrateIndexes(report)
+}
+
+// migrateIndexes creates the missing indexes of the model table
+func (s *Session) migrateIndexes(report *MigrationReport) error {
+	table := s.RefTable()
+	for _, index := range table.Indexes {
+		if s.HasIndex(index.Name) {
+			continue
+		}
+		query, err := s.dialect.CreateIndexSQL(index.Dialect(table.SqlName))
+		if err != nil {
+			return err
+		}
+		change := MigrationChange{Table: table.SqlName, Action: CreateIndexAction, Name: index.Name, SQL: query}
+		if err = s.apply(change, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addUniqueIndex enforces the unique constraint of an added column with a
+// unique index, sqlite cannot add a UNIQUE column to an existing table
+func (s *Session) addUniqueIndex(field *schema.Field, report *MigrationReport) error {
+	table := s.RefTable().SqlName
+	index := &dialect.Index{Table: table, Name: fmt.Sprintf("uni_%s_%s", table, field.SqlName), Unique: true, Columns: []string{field.SqlName}}
+	query, err := s.dialect.CreateIndexSQL(index)
+	if err != nil {
+		return err
+	}
+	return s.apply(MigrationChange{Table: table, Action: CreateIndexAction, Name: index.Name, SQL: query}, report)
+}
+
+func (s *Session) apply(change MigrationChange, report *MigrationReport) error {
+	if _, err := s.raw(change.SQL, nil).Exec(); err != nil {
+		return err
+	}
+	report.Applied = append(report.Applied, change)
+	return nil
+}
+
+// column is a column of the database
+type column struct {
+	name     string
+	dataType string
+	notNull  bool
+	// defaultValue is the expression of the column default
+	defaultValue sql.NullString
+	// migrated reports the column belongs to a field of the model
+	migrated bool
+}
+
+type columnList []*column
+
+// get finds the column name, case insensitively like the databases do
+func (columns columnList) get(name string) (*column, bool) {
+	for _, c := range columns {
+		if strings.EqualFold(c.name, name) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// columns returns the current columns of the model table
+func (s *Session) columns() (columnList, error) {
+	query, values := s.dialect.ColumnsSQL(s.RefTable().SqlName)
+	rows, err := s.raw(query, values).QueryRows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns columnList
+	for rows.Next() {
+		c := &column{}
+		if err = rows.Scan(&c.name, &c.dataType, &c.notNull, &c.defaultValue); err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+	return columns, rows.Err()
+}
+
+// definition renders the column as it is, for a column out of the model
+func (c *column) definition() string {
+	definition := []string{c.name}
+	if c.dataType != "" {
+		definition = append(definition, c.dataType)
+	}
+	if c.notNull {
+		definition = append(definition, "NOT NULL")
+	}
+	if c.defaultValue.Valid {
+		definition = append(definition, "DEFAULT "+c.defaultValue.String)
+	}
+	return strings.Join(definition, " ")
+}
+
+// addColumnDefinition renders a column added to an existing table, NOT NULL
+// needs a default for the rows already in the table, the unique constraint
+// is a unique index, see addUniqueIndex
+func addColumnDefinition(field *schema.Field) string {
+	column := []string{field.SqlName, field.Type}
+	if (field.NotNull || !field.Nullable) && field.Default != "" {
+		column = append(column, "NOT NULL")
+	}
+	if field.Default != "" {
+		column = append(column, "DEFAULT "+field.Default)
+	}
+	return strings.Join(column, " ")
+}
+
+// alterColumnDefinition renders the column of an alter statement, the
+// primary key and unique constraints are already on the table
+func alterColumnDefinition(field *schema.Field) string {
+	column := []string{field.SqlName, field.Type}
+	if field.NotNull || !field.Nullable && !field.PrimaryKey {
+		column = append(column, "NOT NULL")
+	}
+	if field.Default != "" {
+		column = append(column, "DEFAULT "+field.Default)
+	}
+	return strings.Join(column, " ")
+}
@@ -0,0 +1,183 @@
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/catbugdemo/sorm/dialect"
+	"github.com/stretchr/testify/assert"
+)
+
+type migrateUser struct {
+	Id    int64 `sorm:"primaryKey;autoIncrement"`
+	Name  string
+	Email *string `sorm:"unique"`
+	Age   int     `sorm:"default:18;index"`
+}
+
+func TestAutoMigrate(t *testing.T) {
+	s := sqliteSession(t)
+	_, err := s.Raw("CREATE TABLE migrate_user (id integer PRIMARY KEY, name text NOT NULL)").Exec()
+	assert.Nil(t, err)
+	_, err = s.Raw("INSERT INTO migrate_user (name) VALUES (?), (?)", "a", "b").Exec()
+	assert.Nil(t, err)
+
+	report, err := s.AutoMigrate(&migrateUser{})
+	assert.Nil(t, err)
+	var applied []string
+	for _, change := range report.Applied {
+		applied = append(applied, change.SQL)
+	}
+	assert.Equal(t, []string{
+		"ALTER TABLE migrate_user ADD COLUMN email text",
+		"CREATE UNIQUE INDEX uni_migrate_user_email ON migrate_user (email)",
+		"ALTER TABLE migrate_user ADD COLUMN age int NOT NULL DEFAULT 18",
+		"CREATE INDEX idx_migrate_user_age ON migrate_user (age)",
+	}, applied)
+	assert.Empty(t, report.Skipped)
+
+	var users []migrateUser
+	assert.Nil(t, s.OrderBy("id").Find(&users))
+	assert.Equal(t, []migrateUser{{Id: 1, Name: "a", Age: 18}, {Id: 2, Name: "b", Age: 18}}, users)
+
+	email := "a@x"
+	assert.Nil(t, s.Model(&migrateUser{}).Where("id = ?", 1).Update("email", email))
+	assert.NotNil(t, s.Model(&migrateUser{}).Where("id = ?", 2).Update("email", email))
+
+	// the table matches the model once migrated
+	report, err = s.AutoMigrate(&migrateUser{})
+	assert.Nil(t, err)
+	assert.Empty(t, report.Applied)
+	assert.Empty(t, report.Skipped)
+
+	// a new table is created with its indexes
+	assert.Nil(t, s.Model(&migrateUser{}).DropTable())
+	report, err = s.AutoMigrate(&migrateUser{})
+	assert.Nil(t, err)
+	assert.Equal(t, CreateTableAction, report.Applied[0].Action)
+	assert.True(t, s.Model(&migrateUser{}).HasIndex("idx_migrate_user_age"))
+}
+
+type rebuildUser struct {
+	Id   int64 `sorm:"primaryKey"`
+	Name string
+	Age  int
+}
+
+func TestAutoMigrateRebuild(t *testing.T) {
+	s := sqliteSession(t)
+	_, err := s.Raw("CREATE TABLE rebuild_user (id integer PRIMARY KEY, name text NOT NULL DEFAULT 'x', age text, legacy text NOT NULL DEFAULT 'keep')").Exec()
+	assert.Nil(t, err)
+	_, err = s.Raw("CREATE INDEX idx_rebuild_user_legacy ON rebuild_user (legacy)").Exec()
+	assert.Nil(t, err)
+	_, err = s.Raw("INSERT INTO rebuild_user (name, age, legacy) VALUES (?, ?, ?), (?, ?, ?)", "a", "30", "l1", "b", "40", "l2").Exec()
+	assert.Nil(t, err)
+
+	// sqlite retypes a column by rebuilding the table
+	report, err := s.AutoMigrateWith(MigrateOptions{NarrowTypes: true}, &rebuildUser{})
+	assert.Nil(t, err)
+	assert.Equal(t, AlterColumnAction, report.Applied[0].Action)
+	assert.Equal(t, RebuildTableAction, report.Applied[1].Action)
+
+	columns, err := s.columns()
+	assert.Nil(t, err)
+	var definitions []string
+	for _, c := range columns {
+		definitions = append(definitions, c.definition())
+	}
+	assert.Equal(t, []string{"id INTEGER", "name TEXT NOT NULL DEFAULT 'x'", "age INT", "legacy TEXT NOT NULL DEFAULT 'keep'"}, definitions)
+	assert.True(t, s.Model(&rebuildUser{}).HasIndex("idx_rebuild_user_legacy"))
+
+	var users []rebuildUser
+	assert.Nil(t, s.OrderBy("id").Find(&users))
+	assert.Equal(t, []rebuildUser{{Id: 1, Name: "a", Age: 30}, {Id: 2, Name: "b", Age: 40}}, users)
+	// the defaults of the columns out of the model are kept
+	_, err = s.Raw("INSERT INTO rebuild_user (id) VALUES (?)", 3).Exec()
+	assert.Nil(t, err)
+	var name, legacy string
+	assert.Nil(t, s.Raw("SELECT name, legacy FROM rebuild_user WHERE id = ?", 3).QueryRow().Scan(&name, &legacy))
+	assert.Equal(t, []string{"x", "keep"}, []string{name, legacy})
+
+	// dropping a column drops its indexes
+	report, err = s.AutoMigrateWith(MigrateOptions{DropColumns: true}, &rebuildUser{})
+	assert.Nil(t, err)
+	assert.Equal(t, DropColumnAction, report.Applied[0].Action)
+	assert.Equal(t, CreateIndexAction, report.Skipped[0].Action)
+	columns, err = s.columns()
+	assert.Nil(t, err)
+	_, ok := columns.get("legacy")
+	assert.False(t, ok)
+	assert.False(t, s.Model(&rebuildUser{}).HasIndex("idx_rebuild_user_legacy"))
+}
+
+type rebuildTeam struct {
+	Id   int64 `sorm:"primaryKey"`
+	Name string
+}
+
+func TestAutoMigrateRebuildForeignKeys(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "sorm.db")+"?_foreign_keys=1")
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	// one connection, so the pragmas below apply to every statement
+	db.SetMaxOpenConns(1)
+	dial, _ := dialect.GetDialect("sqlite3")
+	s := New(db, dial)
+	for _, statement := range []string{
+		"CREATE TABLE rebuild_team (id integer PRIMARY KEY, name text, legacy text)",
+		"CREATE TABLE rebuild_player (id integer PRIMARY KEY, team_id int REFERENCES rebuild_team (id) ON DELETE CASCADE)",
+		"CREATE TABLE rebuild_log (name text)",
+		"CREATE TRIGGER trg_rebuild_team AFTER INSERT ON rebuild_team BEGIN INSERT INTO rebuild_log VALUES (new.name); END",
+		"CREATE TRIGGER trg_rebuild_legacy AFTER UPDATE OF legacy ON rebuild_team BEGIN INSERT INTO rebuild_log VALUES (new.legacy); END",
+		"INSERT INTO rebuild_team (id, name) VALUES (1, 'a')",
+		"INSERT INTO rebuild_player (id, team_id) VALUES (1, 1), (2, 1)",
+		// an orphan left while the foreign keys were off
+		"PRAGMA foreign_keys = OFF",
+		"INSERT INTO rebuild_player (id, team_id) VALUES (3, 9)",
+		"PRAGMA foreign_keys = ON",
+	} {
+		_, err = s.Raw(statement).Exec()
+		assert.Nil(t, err, statement)
+	}
+	countRows := func(table string) (n int) {
+		assert.Nil(t, s.Raw("SELECT count(*) FROM "+table).QueryRow().Scan(&n))
+		return n
+	}
+
+	// a broken foreign key rolls the rebuild back
+	_, err = s.AutoMigrateWith(MigrateOptions{DropColumns: true}, &rebuildTeam{})
+	assert.Contains(t, fmt.Sprint(err), "breaks a foreign key")
+	columns, err := s.Model(&rebuildTeam{}).columns()
+	assert.Nil(t, err)
+	_, ok := columns.get("legacy")
+	assert.True(t, ok)
+
+	_, err = s.Raw("DELETE FROM rebuild_player WHERE id = ?", 3).Exec()
+	assert.Nil(t, err)
+	report, err := s.AutoMigrateWith(MigrateOptions{DropColumns: true}, &rebuildTeam{})
+	assert.Nil(t, err)
+	assert.Equal(t, RebuildTableAction, report.Applied[1].Action)
+	assert.Equal(t, CreateTriggerAction, report.Skipped[0].Action)
+	assert.Equal(t, "trg_rebuild_legacy", report.Skipped[0].Name)
+
+	// the referencing rows are kept, the triggers and foreign keys still apply
+	assert.Equal(t, 2, countRows("rebuild_player"))
+	_, err = s.Raw("INSERT INTO rebuild_team (id, name) VALUES (2, 'b')").Exec()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, countRows("rebuild_log"))
+	_, err = s.Raw("INSERT INTO rebuild_player (id, team_id) VALUES (4, 9)").Exec()
+	assert.NotNil(t, err)
+	_, err = s.Raw("DELETE FROM rebuild_team WHERE id = 1").Exec()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, countRows("rebuild_player"))
+
+	// the foreign keys cannot be suspended in the transaction of the session
+	_, err = s.Raw("ALTER TABLE rebuild_team ADD COLUMN legacy text").Exec()
+	assert.Nil(t, err)
+	assert.Nil(t, s.Begin())
+	_, err = s.AutoMigrateWith(MigrateOptions{DropColumns: true}, &rebuildTeam{})
+	assert.NotNil(t, err)
+	assert.Nil(t, s.Rollback())
+}
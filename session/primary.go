@@ -0,0 +1,151 @@
+package session
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/catbugdemo/sorm/clause"
+	"github.com/catbugdemo/sorm/log"
+	"github.com/catbugdemo/sorm/schema"
+)
+
+// Get finds the row of primary key ids into dest, a pointer to a model, one
+// id per key column in order. Into a pointer to a slice it finds the rows of
+// every id, the ids of a composite key given as []interface{}.
+//
+//	var user User
+//	err := s.Get(&user, 1)
+//	var members []Member
+//	err = s.Get(&members, []interface{}{1, 2}, []interface{}{1, 3})
+func (s *Session) Get(dest interface{}, ids ...interface{}) error {
+	table, err := s.modelTable(modelOf(dest))
+	if err != nil {
+		return err
+	}
+	columns := primaryColumns(table)
+	if len(columns) == 0 {
+		return fmt.Errorf("%w: %s", log.ErrMissingPrimaryKey, table.Name)
+	}
+	if reflect.Indirect(reflect.ValueOf(dest)).Kind() != reflect.Slice {
+		if len(ids) != len(columns) {
+			return fmt.Errorf("%w: %s has %d primary key columns, got %d values", log.ErrMissingPrimaryKey, table.Name, len(columns), len(ids))
+		}
+		for i, column := range columns {
+			s.Where(column+" = ?", ids[i])
+		}
+		return s.First(dest)
+	}
+	tuples := make([][]interface{}, 0, len(ids))
+	for _, id := range ids {
+		tuple, ok := id.([]interface{})
+		if !ok {
+			tuple = []interface{}{id}
+		}
+		if len(tuple) != len(columns) {
+			return fmt.Errorf("%w: %s has %d primary key columns, got %d values", log.ErrMissingPrimaryKey, table.Name, len(columns), len(tuple))
+		}
+		tuples = append(tuples, tuple)
+	}
+	return s.Where(clause.InTuple(columns, tuples)).Find(dest)
+}
+
+// Reload reads the row of dest again, found by its primary key
+func (s *Session) Reload(dest interface{}) error {
+	if _, err := s.modelTable(dest); err != nil {
+		return err
+	}
+	if err := s.wherePrimary(dest); err != nil {
+		return err
+	}
+	return s.First(dest)
+}
+
+// DeleteModel deletes the row of dest, found by its primary key, or the rows
+// of a slice of models
+func (s *Session) DeleteModel(dest interface{}) error {
+	if _, err := s.modelTable(modelOf(dest)); err != nil {
+		return err
+	}
+	if err := s.wherePrimary(dest); err != nil {
+		return err
+	}
+	return s.Delete()
+}
+
+// Save inserts dest, a pointer to a model, or updates every column of the
+// row with its primary key in one statement, see OnConflict. A blank primary
+// key is inserted and filled by the database.
+func (s *Session) Save(dest interface{}) error {
+	table, err := s.modelTable(dest)
+	if err != nil {
+		return err
+	}
+	if len(table.PrimaryFields) == 0 {
+		return fmt.Errorf("%w: %s", log.ErrMissingPrimaryKey, table.Name)
+	}
+	if _, blank := primaryValues(table, reflect.ValueOf(dest)); blank {
+		return s.Create(dest)
+	}
+	return s.Select("*").OnConflict(primaryColumns(table)...).UpdateAll().Create(dest)
+}
+
+// wherePrimary matches the row of dest by its primary key, the rows of a
+// slice by the tuples of their keys
+func (s *Session) wherePrimary(dest interface{}) error {
+	table := s.RefTable()
+	destValue := reflect.Indirect(reflect.ValueOf(dest))
+	if destValue.Kind() != reflect.Slice {
+		values, blank := primaryValues(table, destValue)
+		if len(values) == 0 || blank {
+			return fmt.Errorf("%w: %s", log.ErrMissingPrimaryKey, table.Name)
+		}
+		for i, field := range table.PrimaryFields {
+			s.Where(field.SqlName+" = ?", values[i])
+		}
+		return nil
+	}
+	if len(table.PrimaryFields) == 0 {
+		return fmt.Errorf("%w: %s", log.ErrMissingPrimaryKey, table.Name)
+	}
+	tuples := make([][]interface{}, 0, destValue.Len())
+	for i := 0; i < destValue.Len(); i++ {
+		values, blank := primaryValues(table, destValue.Index(i))
+		if blank {
+			return fmt.Errorf("%w: %s", log.ErrMissingPrimaryKey, table.Name)
+		}
+		tuples = append(tuples, values)
+	}
+	s.Where(clause.InTuple(primaryColumns(table), tuples))
+	return nil
+}
+
+// primaryValues returns the primary key of dest, blank when a column is
+func primaryValues(table *schema.Schema, dest reflect.Value) (values []interface{}, blank bool) {
+	dest = reflect.Indirect(dest)
+	for _, field := range table.PrimaryFields {
+		value := field.ValueOf(dest)
+		if !value.IsValid() || schema.IsBlank(value) {
+			return nil, true
+		}
+		values = append(values, value.Interface())
+	}
+	return values, false
+}
+
+// primaryColumns returns the columns of the primary key of table in order
+func primaryColumns(table *schema.Schema) []string {
+	columns := make([]string, 0, len(table.PrimaryFields))
+	for _, field := range table.PrimaryFields {
+		columns = append(columns, field.SqlName)
+	}
+	return columns
+}
+
+// modelOf returns a model of dest, a pointer to a model or to a slice of them
+func modelOf(dest interface{}) interface{} {
+	destValue := reflect.Indirect(reflect.ValueOf(dest))
+	if destValue.Kind() != reflect.Slice {
+		return dest
+	}
+	return reflect.New(indirect(destValue.Type().Elem())).Interface()
+}
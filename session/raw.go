@@ -1,14 +1,13 @@
 package session
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"github.com/catbugdemo/sorm/clause"
 	"github.com/catbugdemo/sorm/dialect"
 	"github.com/catbugdemo/sorm/log"
 	"github.com/catbugdemo/sorm/schema"
-	"reflect"
-	"strconv"
 	"strings"
 )
 
@@ -20,14 +19,36 @@ type Session struct {
 	clause   clause.Clause
 	sql      strings.Builder
 	sqlVars  []interface{}
+	// where holds the conditions of Where, Or and Not
+	where clause.Expression
+	// having holds the conditions of Having
+	having clause.Expression
+	joins  []join
+	// ctes are the common table expressions of With and WithRecursive
+	ctes []cte
+	// conflict handles the conflicts of Insert, see OnConflict
+	conflict *Conflict
+	// selects are the columns of Select and Distinct, distinct renders SELECT DISTINCT
+	selects    []string
+	selectVars []interface{}
+	// omits are the columns of Omit
+	omits    []string
+	distinct bool
 	content  Content
+	ctx      context.Context
+	namer    schema.Namer
+	// dryRun records the statements instead of executing them, see DryRun
+	dryRun     bool
+	statements []Statement
+	// err fails the pending statement, such as the error of an invalid model
+	err error
 }
 
 // CommonDB is a minimal function set of db
 type CommonDB interface {
-	Query(query string, args ...interface{}) (*sql.Rows, error)
-	QueryRow(qury string, args ...interface{}) *sql.Row
-	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
 var _ CommonDB = (*sql.DB)(nil)
@@ -37,13 +58,51 @@ func New(db *sql.DB, dialect dialect.Dialect) *Session {
 	return &Session{
 		db:      db,
 		dialect: dialect,
+		namer:   schema.DefaultNamingStrategy,
 	}
 }
 
+// SetNamingStrategy sets the Namer used to derive table and column names
+func (s *Session) SetNamingStrategy(namer schema.Namer) *Session {
+	s.namer = namer
+	s.refTable = nil
+	return s
+}
+
 func (s *Session) Clear() {
 	s.sql.Reset()
 	s.sqlVars = nil
 	s.clause = clause.Clause{}
+	s.where = nil
+	s.having = nil
+	s.joins = nil
+	s.ctes = nil
+	s.conflict = nil
+	s.selects = nil
+	s.selectVars = nil
+	s.omits = nil
+	s.distinct = false
+	s.err = nil
+}
+
+// WithContext binds ctx to the session, every statement executed afterwards
+// (including Begin) is bound to it and aborted once ctx is done.
+func (s *Session) WithContext(ctx context.Context) *Session {
+	s.ctx = ctx
+	return s
+}
+
+// Context returns the context bound by WithContext, or context.Background
+func (s *Session) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// Dialect returns the dialect of the database of the session
+func (s *Session) Dialect() dialect.Dialect {
+	return s.dialect
 }
 
 func (s *Session) DB() CommonDB {
@@ -54,78 +113,140 @@ func (s *Session) DB() CommonDB {
 }
 
 // Raw 原生查询
+// a single map or struct argument binds :name and @name parameters
 func (s *Session) Raw(sql string, values ...interface{}) *Session {
+	return s.raw(s.bindNamed(sql, values))
+}
+
+// raw appends generated sql, which only uses ? placeholders
+func (s *Session) raw(sql string, values []interface{}) *Session {
 	s.sql.WriteString(sql)
 	s.sql.WriteString(" ")
 	s.sqlVars = append(s.sqlVars, values...)
 	return s
 }
 
+// Row is the result of QueryRow, it reports errors found while binding
+// the statement as well as the ones returned by the driver
+type Row struct {
+	row *sql.Row
+	err error
+}
+
+// Scan copies the columns of the matched row into dest
+func (r *Row) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.row.Scan(dest...)
+}
+
+// Err reports the error of the query without scanning the row
+func (r *Row) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.row.Err()
+}
+
+// bind renders the pending statement for the dialect of the session
+func (s *Session) bind() (string, []interface{}, error) {
+	if s.err != nil {
+		return "", nil, s.err
+	}
+	sql, sqlVars, logs, err := BindVarsSyntax(s.sql.String(), s.sqlVars, s.dialect.BindVarStyle(), s.dialect.Syntax())
+	if err != nil {
+		log.Error(err)
+		return "", nil, err
+	}
+	log.Info(": " + logs)
+	return sql, sqlVars, nil
+}
+
 // Exec raw sql with sqlVars
 func (s *Session) Exec() (result sql.Result, err error) {
 	defer s.Clear()
-	// 将 ？ 改成 $num
-	sql, sqlVars, logs := QueToDoller(s.sql.String(), s.sqlVars)
-	log.Info(": " + logs)
-	if result, err = s.DB().Exec(sql, sqlVars...); err != nil {
+	sql, sqlVars, err := s.bind()
+	if err != nil {
+		return nil, err
+	}
+	if s.dryRun {
+		return nil, s.record(sql, sqlVars)
+	}
+	if result, err = s.DB().ExecContext(s.Context(), sql, sqlVars...); err != nil {
+		err = s.wrapErr(err)
 		log.Error(err)
 	}
 	return
 }
 
-// QueryRow gets a record from db
-func (s *Session) QueryRow() *sql.Row {
+// QueryRow gets a record from db, an error of binding the statement is
+// returned by Scan like the errors of the driver
+func (s *Session) QueryRow() *Row {
 	defer s.Clear()
-	sql, sqlVars, logs := QueToDoller(s.sql.String(), s.sqlVars)
-	log.Info(": " + logs)
-	return s.DB().QueryRow(sql, sqlVars...)
+	sql, sqlVars, err := s.bind()
+	if err != nil {
+		return &Row{err: err}
+	}
+	if s.dryRun {
+		return &Row{err: s.record(sql, sqlVars)}
+	}
+	return &Row{row: s.DB().QueryRowContext(s.Context(), sql, sqlVars...)}
 }
 
 // QueryRows gets a list of records from db
 func (s *Session) QueryRows() (rows *sql.Rows, err error) {
 	defer s.Clear()
-	sql, sqlVars, logs := QueToDoller(s.sql.String(), s.sqlVars)
-	log.Info(": " + logs)
-	if rows, err = s.DB().Query(sql, sqlVars...); err != nil {
+	sql, sqlVars, err := s.bind()
+	if err != nil {
+		return nil, err
+	}
+	if s.dryRun {
+		return nil, s.record(sql, sqlVars)
+	}
+	if rows, err = s.DB().QueryContext(s.Context(), sql, sqlVars...); err != nil {
+		err = s.wrapErr(err)
 		log.Error(err)
 	}
 	return
 }
 
+// canceledError reports a statement aborted by the session context,
+// it matches both log.ErrQueryCanceled and the underlying context error
+type canceledError struct {
+	err error
+}
+
+func (e *canceledError) Error() string {
+	return log.ErrQueryCanceled.Error() + ": " + e.err.Error()
+}
+
+func (e *canceledError) Is(target error) bool {
+	return target == log.ErrQueryCanceled
+}
+
+func (e *canceledError) Unwrap() error {
+	return e.err
+}
+
+// wrapErr turns err into a canceledError when the session context is done
+func (s *Session) wrapErr(err error) error {
+	if err == nil || s.ctx == nil || s.ctx.Err() == nil {
+		return err
+	}
+	if errors.Is(err, log.ErrQueryCanceled) {
+		return err
+	}
+	return &canceledError{err: s.ctx.Err()}
+}
+
 // QueToDoller  ? to $num
+//
+// Deprecated: use BindVars, which reports malformed statements as errors
 func QueToDoller(sql string, vars []interface{}) (string, []interface{}, string) {
-	sql = strings.ReplaceAll(sql, " in ", " IN ")
-	if strings.Contains(sql, " IN ") {
-		split := strings.Split(sql, " IN ")
-		count := strings.Count(split[0], "?")
-		for i := 1; i < len(split); i++ {
-			reflectValue := reflect.Indirect(reflect.ValueOf(vars[count]))
-			switch reflectValue.Kind() {
-			case reflect.Slice, reflect.Array:
-				reflectLen := reflectValue.Len()
-				vars = append(vars[:count], vars[count+1:]...) // delete slice
-				for j := 0; j < reflectLen; j++ {
-					vars = append(vars[:count+j], append([]interface{}{reflectValue.Index(j).Interface()}, vars[count+j:]...)...)
-				}
-				// 修改 ? 数量
-				repeat := strings.Repeat("?,", reflectLen)
-				split[i] = strings.Replace(split[i], "?", repeat[:len(repeat)-1], 1)
-			}
-			count += strings.Count(split[i], "?")
-		}
-		// 计数 ? 数量
-		sql = strings.Join(split, " IN ")
-	}
-	// log
-	queCount := strings.Count(sql, "?")
-	logs := sql
-	for i := 0; i < queCount; i++ {
-		logs = strings.Replace(logs, "?", "'"+fmt.Sprintf("%v", vars[i])+"'", 1)
-	}
-
-	// ? to $num
-	for i := 0; i < queCount; i++ {
-		sql = strings.Replace(sql, "?", "$"+strconv.Itoa(i+1), 1)
+	sql, vars, logs, err := BindVars(sql, vars, dialect.Dollar)
+	if err != nil {
+		log.Error(err)
 	}
 	return sql, vars, logs
 }
@@ -0,0 +1,55 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/catbugdemo/sorm/dialect"
+	"github.com/catbugdemo/sorm/log"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+// sqliteSession opens a session on a new sqlite database of the test
+func sqliteSession(t *testing.T) *Session {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sorm.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	dial, _ := dialect.GetDialect("sqlite3")
+	return New(db, dial)
+}
+
+func TestWithContext(t *testing.T) {
+	s := sqliteSession(t)
+	_, err := s.Raw("CREATE TABLE ctx_user (id integer PRIMARY KEY, name text)").Exec()
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.WithContext(ctx).Raw("INSERT INTO ctx_user(name) VALUES (?)", "a").Exec()
+	assert.ErrorIs(t, err, log.ErrQueryCanceled)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, s.Begin(), log.ErrQueryCanceled)
+
+	// a transaction aborted by its context is rolled back
+	ctx, cancel = context.WithCancel(context.Background())
+	s.WithContext(ctx)
+	assert.Nil(t, s.Begin())
+	_, err = s.Raw("INSERT INTO ctx_user(name) VALUES (?)", "a").Exec()
+	assert.Nil(t, err)
+	cancel()
+	_, err = s.Raw("INSERT INTO ctx_user(name) VALUES (?)", "b").Exec()
+	assert.ErrorIs(t, err, log.ErrQueryCanceled)
+	// database/sql may have rolled back the transaction already
+	if err = s.Rollback(); err != nil {
+		assert.ErrorIs(t, err, log.ErrQueryCanceled)
+	}
+
+	var count int
+	assert.Nil(t, New(s.db, s.dialect).Raw("SELECT count(*) FROM ctx_user").QueryRow().Scan(&count))
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,282 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/catbugdemo/sorm/log"
+	"github.com/catbugdemo/sorm/schema"
+)
+
+// rebuildPlan is the table rebuildTable creates in place of the model table,
+// it applies the changes the dialect cannot alter in place, such as dropping
+// or retyping a column on sqlite
+type rebuildPlan struct {
+	// definitions are the columns of the new table
+	definitions []string
+	// copied are the columns copied from the current table
+	copied []string
+	// dropped are the columns left out of the new table
+	dropped []string
+	// primaryKey is the constraint of a composite primary key
+	primaryKey string
+	changes    []MigrationChange
+}
+
+// keep copies the column name to the new table as definition
+func (plan *rebuildPlan) keep(name, definition string) {
+	plan.definitions = append(plan.definitions, definition)
+	plan.copied = append(plan.copied, name)
+}
+
+// change copies the column name to the new table as definition, applying change
+func (plan *rebuildPlan) change(change MigrationChange, name, definition string) {
+	plan.keep(name, definition)
+	plan.changes = append(plan.changes, change)
+}
+
+// drop leaves the column name out of the new table, applying change
+func (plan *rebuildPlan) drop(change MigrationChange, name string) {
+	plan.dropped = append(plan.dropped, name)
+	plan.changes = append(plan.changes, change)
+}
+
+// rebuildDefinition renders field with the type and nullability of current,
+// and its default unless the model declares one, the constraints the model
+// cannot tell are kept as they are, a composite primary key is declared by
+// the table
+func rebuildDefinition(field *schema.Field, current *column, composite bool) string {
+	definition := []string{field.SqlName, current.dataType}
+	if field.PrimaryKey && !composite {
+		definition = append(definition, "PRIMARY KEY")
+	}
+	if current.notNull {
+		definition = append(definition, "NOT NULL")
+	}
+	if field.Unique && !field.PrimaryKey {
+		definition = append(definition, "UNIQUE")
+	}
+	if field.Default != "" {
+		definition = append(definition, "DEFAULT "+field.Default)
+	} else if current.defaultValue.Valid {
+		definition = append(definition, "DEFAULT "+current.defaultValue.String)
+	}
+	return strings.Join(definition, " ")
+}
+
+// rebuildTable applies plan in a transaction: creates the new table, copies
+// the rows, drops the current table, renames the new one and recreates the
+// indexes and triggers but the ones on dropped columns. The foreign keys are
+// suspended meanwhile and checked before commit.
+func (s *Session) rebuildTable(plan *rebuildPlan, report *MigrationReport) (err error) {
+	table := s.RefTable().SqlName
+	indexes, err := s.definitions(s.dialect.IndexesSQL(table))
+	if err != nil {
+		return err
+	}
+	triggers, err := s.definitions(s.dialect.TriggersSQL(table))
+	if err != nil {
+		return err
+	}
+
+	definitions := plan.definitions
+	if plan.primaryKey != "" {
+		definitions = append(definitions, plan.primaryKey)
+	}
+	tmp := table + "__sorm_rebuild"
+	columns := strings.Join(plan.copied, ",")
+	statements := []string{
+		fmt.Sprintf("CREATE TABLE %s (%s)", tmp, strings.Join(definitions, ",")),
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", tmp, columns, columns, table),
+		fmt.Sprintf("DROP TABLE %s", table),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", tmp, table),
+	}
+	var skipped []MigrationChange
+	for _, index := range indexes {
+		if name, ok := index.uses(plan.dropped); ok {
+			skipped = append(skipped, MigrationChange{Table: table, Action: CreateIndexAction, Name: index.name, SQL: index.sql,
+				Reason: fmt.Sprintf("the index uses the dropped column %s", name)})
+			continue
+		}
+		statements = append(statements, index.sql)
+	}
+	for _, trigger := range triggers {
+		if name, ok := trigger.mentions(plan.dropped); ok {
+			skipped = append(skipped, MigrationChange{Table: table, Action: CreateTriggerAction, Name: trigger.name, SQL: trigger.sql,
+				Reason: fmt.Sprintf("the trigger uses the dropped column %s", name)})
+			continue
+		}
+		statements = append(statements, trigger.sql)
+	}
+
+	switch {
+	case s.dryRun:
+		err = s.execRebuild(statements, false)
+	case s.tx != nil:
+		// the foreign keys cannot be suspended inside a transaction
+		var enforced bool
+		if enforced, err = s.foreignKeysEnforced(); err != nil {
+			return err
+		}
+		if enforced {
+			return fmt.Errorf("%s cannot be rebuilt in a transaction enforcing foreign keys", table)
+		}
+		err = s.execRebuild(statements, false)
+	default:
+		err = s.rebuildInTransaction(statements)
+	}
+	if err != nil {
+		return err
+	}
+
+	report.Applied = append(report.Applied, plan.changes...)
+	report.Applied = append(report.Applied, MigrationChange{Table: table, Action: RebuildTableAction, SQL: strings.Join(statements, ";\n")})
+	report.Skipped = append(report.Skipped, skipped...)
+	return nil
+}
+
+// rebuildInTransaction executes the statements of a rebuild in a transaction
+// of one connection, which enforces no foreign key meanwhile
+func (s *Session) rebuildInTransaction(statements []string) (err error) {
+	conn, err := s.db.Conn(s.Context())
+	if err != nil {
+		return s.wrapErr(err)
+	}
+	defer conn.Close()
+	tx := s.clone()
+	tx.Clear()
+
+	keys := s.dialect.ForeignKeys()
+	var enforced bool
+	if keys != nil {
+		if err = conn.QueryRowContext(s.Context(), keys.EnabledSQL).Scan(&enforced); err != nil {
+			return s.wrapErr(err)
+		}
+	}
+	if enforced {
+		if _, err = conn.ExecContext(s.Context(), keys.DisableSQL); err != nil {
+			return s.wrapErr(err)
+		}
+		defer func() {
+			// enforced again even when the session context is done
+			if _, e := conn.ExecContext(context.Background(), keys.EnableSQL); e != nil && err == nil {
+				err = e
+			}
+		}()
+	}
+
+	log.Info("transaction begin")
+	if tx.tx, err = conn.BeginTx(s.Context(), nil); err != nil {
+		return s.wrapErr(err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	return tx.execRebuild(statements, enforced)
+}
+
+// execRebuild executes statements, then lists the rows violating a foreign
+// key when check is set
+func (s *Session) execRebuild(statements []string, check bool) error {
+	for _, statement := range statements {
+		if _, err := s.raw(statement, nil).Exec(); err != nil {
+			return err
+		}
+	}
+	if !check {
+		return nil
+	}
+	rows, err := s.raw(s.dialect.ForeignKeys().CheckSQL, nil).QueryRows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var violation []string
+		columns, _ := rows.Columns()
+		values := make([]interface{}, len(columns))
+		for i := range values {
+			values[i] = new(interface{})
+		}
+		if err = rows.Scan(values...); err != nil {
+			return s.wrapErr(err)
+		}
+		for i, column := range columns {
+			violation = append(violation, fmt.Sprintf("%s=%v", column, *values[i].(*interface{})))
+		}
+		return fmt.Errorf("rebuilding %s breaks a foreign key: %s", s.RefTable().SqlName, strings.Join(violation, " "))
+	}
+	return s.wrapErr(rows.Err())
+}
+
+// foreignKeysEnforced reports whether the connection of the session enforces
+// foreign keys, which a rebuild must suspend
+func (s *Session) foreignKeysEnforced() (bool, error) {
+	keys := s.dialect.ForeignKeys()
+	if keys == nil {
+		return false, nil
+	}
+	var enforced bool
+	if err := s.raw(keys.EnabledSQL, nil).QueryRow().Scan(&enforced); err != nil {
+		return false, s.wrapErr(err)
+	}
+	return enforced, nil
+}
+
+// definition is an index or a trigger of the database and its create statement
+type definition struct {
+	name string
+	sql  string
+}
+
+// uses reports the first of columns the index is built on
+func (index definition) uses(columns []string) (string, bool) {
+	// the columns follow the table name, which may contain a column name
+	body := index.sql
+	if i := strings.Index(body, "("); i >= 0 {
+		body = body[i:]
+	}
+	return mentions(body, columns)
+}
+
+// mentions reports the first of columns the trigger mentions
+func (trigger definition) mentions(columns []string) (string, bool) {
+	return mentions(trigger.sql, columns)
+}
+
+func mentions(sql string, columns []string) (string, bool) {
+	for _, name := range columns {
+		if regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(name) + `\b`).MatchString(sql) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// definitions returns the indexes or triggers listed by query
+func (s *Session) definitions(query string, values []interface{}) ([]definition, error) {
+	rows, err := s.raw(query, values).QueryRows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var definitions []definition
+	for rows.Next() {
+		var d definition
+		if err = rows.Scan(&d.name, &d.sql); err != nil {
+			return nil, err
+		}
+		d.sql = strings.TrimSpace(d.sql)
+		definitions = append(definitions, d)
+	}
+	return definitions, rows.Err()
+}
@@ -2,9 +2,12 @@ package session
 
 import (
 	"errors"
+	"fmt"
 	"github.com/catbugdemo/sorm/clause"
 	"github.com/catbugdemo/sorm/log"
+	"github.com/catbugdemo/sorm/schema"
 	"reflect"
+	"strings"
 )
 
 func (s *Session) Create(values interface{}) error {
@@ -25,91 +28,262 @@ func (s *Session) Create(values interface{}) error {
 	return nil
 }
 
+// Insert inserts the elements of values, a slice or an array, and writes the
+// inserted rows back when given a pointer. Rows are split into statements binding less than
+// the MaxBindVars of the dialect, executed in one transaction.
 func (s *Session) Insert(values interface{}) error {
-	recordValues := make([]interface{}, 0)
-	for _, value := range insertInBatches(values) {
+	return s.CreateInBatches(values, 0)
+}
+
+// CreateInBatches inserts the elements of values like Insert, at most size
+// rows per statement, 0 for as many as the dialect binds
+func (s *Session) CreateInBatches(values interface{}, size int) (err error) {
+	destSlice := reflect.Indirect(reflect.ValueOf(values))
+	if kind := destSlice.Kind(); kind != reflect.Slice && kind != reflect.Array {
+		return s.Create(values)
+	}
+	if !destSlice.CanAddr() {
+		// an array given by value is sliced from a copy, nothing is written back
+		array := reflect.New(destSlice.Type()).Elem()
+		array.Set(destSlice)
+		destSlice = array
+	}
+	batches := s.insertBatches(destSlice, size)
+	if len(batches) > 1 && s.tx == nil && !s.dryRun {
+		if err = s.Begin(); err != nil {
+			return err
+		}
+		defer func() {
+			if p := recover(); p != nil {
+				_ = s.Rollback()
+				s.tx = nil
+				panic(p)
+			} else if err != nil {
+				_ = s.Rollback()
+			} else {
+				err = s.Commit()
+			}
+			s.tx = nil
+		}()
+	}
+	// the clauses are cleared once a statement is executed
+	conflict := s.conflict
+	for _, batch := range batches {
+		s.conflict = conflict
+		if err = s.insert(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertBatch is a slice of rows inserted by one statement
+type insertBatch struct {
+	// rows are the elements of the inserted slice
+	rows    reflect.Value
+	columns []string
+	values  []interface{}
+}
+
+// insertBatches splits destSlice into batches of at most size rows binding
+// at most MaxBindVars arguments, rows with blank fields insert fewer columns
+// so a batch holds consecutive rows inserting the same columns
+func (s *Session) insertBatches(destSlice reflect.Value, size int) []*insertBatch {
+	var batches []*insertBatch
+	var batch *insertBatch
+	start := 0
+	for i := 0; i < destSlice.Len(); i++ {
+		value := destSlice.Index(i).Interface()
 		table := s.Model(value).RefTable()
 		s.CallMethod(BeforeInsert, value)
-		if len(recordValues) == 0 { // 添加返回数据
-			recordValues = append(recordValues, table.FieldNames)
+		columns, vars := table.SelectValues(value, s.writeSelects(), s.omits)
+
+		rows := s.dialect.MaxBindVars()
+		if len(columns) > 0 {
+			rows /= len(columns)
+		}
+		if size > 0 && size < rows {
+			rows = size
+		}
+		if batch == nil || i-start >= rows || !equalColumns(batch.columns, columns) {
+			batch = &insertBatch{columns: columns}
+			batches = append(batches, batch)
+			start = i
 		}
-		fieldSqlNames, fieldValues := table.RecordValues(value)
-		s.clause.Set(clause.INSERT, table.SqlName, fieldSqlNames)
-		recordValues = append(recordValues, fieldValues)
+		batch.rows = destSlice.Slice(start, i+1)
+		batch.values = append(batch.values, vars)
 	}
-	s.clause.Set(clause.VALUES, recordValues...)
-	sql, vars := s.clause.Build(clause.INSERT, clause.VALUES)
-	rows, err := s.Raw(sql, vars...).QueryRows()
+	return batches
+}
+
+func equalColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// insert executes the statement of batch and writes the returned rows back
+func (s *Session) insert(batch *insertBatch) error {
+	s.clause.Set(clause.INSERT, s.RefTable().SqlName, batch.columns)
+	s.clause.Set(clause.VALUES, batch.values...)
+	s.setConflict(batch.columns)
+	if !s.dialect.SupportReturning() {
+		return s.insertWithoutReturning(batch.rows)
+	}
+	conflict := s.conflict
+	s.clause.Set(clause.RETURNING, s.RefTable().FieldNames)
+	sql, vars := s.clause.Build(clause.INSERT, clause.VALUES, clause.ONCONFLICT, clause.RETURNING)
+	rows, err := s.raw(sql, vars).QueryRows()
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 
 	// binding returning
-	destSlice := reflect.Indirect(reflect.ValueOf(values))
-	destType := destSlice.Type().Elem()
-	var index int
+	destType := batch.rows.Type().Elem()
+	var returned []reflect.Value
 	for rows.Next() {
 		dest := reflect.New(destType).Elem()
 		var result []interface{}
 		for _, field := range s.RefTable().Fields {
-			result = append(result, dest.FieldByName(field.Name).Addr().Interface())
+			result = append(result, field.Addressable(dest).Addr().Interface())
 		}
 		if err = rows.Scan(result...); err != nil {
-			return err
+			return s.wrapErr(err)
 		}
 		s.CallMethod(AfterInsert, nil)
-		destSlice.Index(index).Set(dest)
-		index++
+		returned = append(returned, dest)
 	}
-	log.Info("INSERT affects rows:", index)
+	if err = rows.Err(); err != nil {
+		return s.wrapErr(err)
+	}
+	s.setReturning(batch.rows, returned, conflict)
+	log.Info("INSERT affects rows:", len(returned))
 	return nil
 }
 
+// insertWithoutReturning executes the insert for dialects without RETURNING,
+// the auto increment primary key is filled from LastInsertId, which reports
+// the id of the first row of a multi-row insert, which is not reliable once
+// a conflict updated rows so upserts leave the primary keys as they are
+func (s *Session) insertWithoutReturning(destSlice reflect.Value) error {
+	upsert := s.conflict != nil
+	sql, vars := s.clause.Build(clause.INSERT, clause.VALUES, clause.ONCONFLICT)
+	result, err := s.raw(sql, vars).Exec()
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// the id generated fills a single primary key, a composite key is given
+	if primary := s.RefTable().PrimaryField(); len(s.RefTable().PrimaryFields) == 1 && !upsert {
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		fillInsertIds(primary, destSlice, id)
+	}
+	for i := 0; i < destSlice.Len(); i++ {
+		s.CallMethod(AfterInsert, destSlice.Index(i).Addr().Interface())
+	}
+	log.Info("INSERT affects rows:", affected)
+	return nil
+}
+
+// fillInsertIds sets the blank primary keys of rows to the ids generated
+// from id on, the rows given a primary key do not use one
+func fillInsertIds(primary *schema.Field, rows reflect.Value, id int64) {
+	for i := 0; i < rows.Len(); i++ {
+		field := primary.Addressable(rows.Index(i))
+		if !schema.IsBlank(field) {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			field.SetInt(id)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			field.SetUint(uint64(id))
+		default:
+			continue
+		}
+		id++
+	}
+}
+
 func (s *Session) Find(values interface{}) error {
 	destSlice := reflect.Indirect(reflect.ValueOf(values))
 	destType := destSlice.Type().Elem()
-	s.Model(reflect.New(destType).Elem().Interface())
+	table := s.Model(reflect.New(destType).Elem().Interface()).RefTable()
 	s.CallMethod(BeforeQuery, nil)
-	s.clause.Set(clause.SELECT, s.content.SelectFields)
-	s.clause.Set(clause.TABLE, s.content.TableName)
-	sql, vars := s.clause.Build(clause.SELECT, clause.TABLE, clause.WHERE, clause.ORDERBY, clause.LIMIT, clause.OFFSET)
-	rows, err := s.Raw(sql, vars...).QueryRows()
+	from := s.from()
+	switch {
+	case len(s.selects) > 0:
+		s.setSelect(s.selects, s.selectVars...)
+	case len(s.joins) > 0:
+		s.setSelect(s.joinSelect(table, from))
+	default:
+		s.setSelect(s.content.SelectFields)
+	}
+	rows, err := s.raw(s.selectSQL()).QueryRows()
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 
+	// the columns are matched by name, "user.name" and "name" both fill Name
+	columns, err := rows.Columns()
+	if err != nil {
+		return s.wrapErr(err)
+	}
+	fields := make([]*schema.Field, len(columns))
+	for i, name := range columns {
+		fields[i] = table.GetFieldBySqlName(name[strings.LastIndexByte(name, '.')+1:])
+	}
 	for rows.Next() {
 		dest := reflect.New(destType).Elem()
-		var result []interface{}
-		for _, field := range s.content.SelectFields {
-			result = append(result, dest.FieldByName(s.RefTable().FieldSqlMap[field]).Addr().Interface())
+		result := make([]interface{}, len(fields))
+		// the columns of structs embedded through a nil pointer are scanned
+		// through a pointer, the struct is only allocated for a non NULL value
+		holders := make(map[*schema.Field]reflect.Value)
+		for i, field := range fields {
+			switch {
+			case field == nil:
+				result[i] = new(interface{})
+			case field.ValueOf(dest).IsValid():
+				result[i] = field.Addressable(dest).Addr().Interface()
+			default:
+				holder := reflect.New(reflect.PtrTo(destType.FieldByIndex(field.Index).Type))
+				holders[field] = holder
+				result[i] = holder.Interface()
+			}
 		}
 		if err = rows.Scan(result...); err != nil {
-			return err
+			return s.wrapErr(err)
+		}
+		for field, holder := range holders {
+			if value := holder.Elem(); !value.IsNil() {
+				field.Addressable(dest).Set(value.Elem())
+			}
 		}
 		s.CallMethod(AfterQuery, dest.Addr().Interface())
 		destSlice.Set(reflect.Append(destSlice, dest))
 	}
+	if err = rows.Err(); err != nil {
+		return s.wrapErr(err)
+	}
 	if destSlice.Len() == 0 {
 		return log.ErrRecordNotFound
 	}
-	return rows.Close()
-}
-
-// insertInBatches imitate gorm CreateInBatches
-func insertInBatches(value interface{}) []interface{} {
-	values := make([]interface{}, 0)
-	reflectValue := reflect.Indirect(reflect.ValueOf(value))
-	switch reflectValue.Kind() {
-	case reflect.Slice, reflect.Array:
-		reflectLen := reflectValue.Len()
-		for i := 0; i < reflectLen; i++ {
-			values = append(values, reflectValue.Index(i).Interface())
-		}
-	default:
-		values = append(values, value)
-	}
-	return values
+	return nil
 }
 
 // support kv list: "SqlName", "Tom", "Age", 18, ....
@@ -122,7 +296,7 @@ func (s *Session) Update(kv ...interface{}) error {
 
 	s.clause.Set(clause.UPDATE, s.content.TableName, m)
 	sql, vars := s.clause.Build(clause.UPDATE, clause.WHERE)
-	result, err := s.Raw(sql, vars...).Exec()
+	result, err := s.raw(sql, vars).Exec()
 	if err != nil {
 		return err
 	}
@@ -143,13 +317,17 @@ func (s *Session) Update(kv ...interface{}) error {
 func (s *Session) Updates(values interface{}) error {
 	s.CallMethod(BeforeUpdate, nil)
 	m := make(map[string]interface{})
-	dest := reflect.ValueOf(values)
+	dest := reflect.Indirect(reflect.ValueOf(values))
 	switch dest.Kind() {
 	case reflect.Map:
-		m = values.(map[string]interface{})
+		for column, value := range values.(map[string]interface{}) {
+			if !contains(s.omits, column) {
+				m[column] = value
+			}
+		}
 	case reflect.Struct:
-		table := s.Model(values).RefTable()
-		fieldSqlNames, fieldValues := table.RecordValues(values)
+		table := s.Model(dest.Interface()).RefTable()
+		fieldSqlNames, fieldValues := table.SelectValues(values, s.writeSelects(), s.omits)
 		for i, sqlName := range fieldSqlNames {
 			m[sqlName] = fieldValues[i]
 		}
@@ -159,7 +337,7 @@ func (s *Session) Updates(values interface{}) error {
 
 	s.clause.Set(clause.UPDATE, s.content.TableName, m)
 	sql, vars := s.clause.Build(clause.UPDATE, clause.WHERE)
-	result, err := s.Raw(sql, vars...).Exec()
+	result, err := s.raw(sql, vars).Exec()
 	if err != nil {
 		return err
 	}
@@ -179,7 +357,7 @@ func (s *Session) Delete() error {
 	s.CallMethod(BeforeDelete, nil)
 	s.clause.Set(clause.DELETE, s.content.TableName)
 	sql, vars := s.clause.Build(clause.DELETE, clause.WHERE)
-	result, err := s.Raw(sql, vars...).Exec()
+	result, err := s.raw(sql, vars).Exec()
 	if err != nil {
 		return err
 	}
@@ -195,13 +373,33 @@ func (s *Session) Delete() error {
 	return nil
 }
 
+// Count counts the matched rows, with GroupBy it counts the groups and with
+// Distinct the distinct rows of the selected columns
 func (s *Session) Count(values interface{}) error {
-	s.clause.Set(clause.COUNT, s.RefTable().SqlName)
-	s.clause.Set(clause.TABLE, s.content.TableName)
-	sql, vars := s.clause.Build(clause.COUNT, clause.TABLE, clause.WHERE)
-	row := s.Raw(sql, vars...).QueryRow()
+	s.from()
+	var sql string
+	var vars []interface{}
+	if group, _ := s.clause.Get(clause.GROUPBY); group != "" || s.distinct {
+		switch {
+		case len(s.selects) > 0:
+			s.setSelect(s.selects, s.selectVars...)
+		case group != "":
+			// a grouped query may only select the grouped expressions
+			s.setSelect([]string{strings.TrimPrefix(group, "GROUP BY ")})
+		default:
+			s.setSelect([]string{"*"})
+		}
+		sql, vars = s.clause.Build(clause.DISTINCT, clause.SELECT, clause.TABLE, clause.JOIN, clause.WHERE, clause.GROUPBY, clause.HAVING)
+		with, withVars := s.clause.Build(clause.WITH)
+		sql = strings.TrimSpace(fmt.Sprintf("%s SELECT count(*) FROM (%s) sorm_count", with, sql))
+		vars = append(withVars, vars...)
+	} else {
+		s.clause.Set(clause.COUNT)
+		sql, vars = s.clause.Build(clause.WITH, clause.COUNT, clause.TABLE, clause.JOIN, clause.WHERE)
+	}
+	row := s.raw(sql, vars).QueryRow()
 	if err := row.Scan(values); err != nil {
-		return err
+		return s.wrapErr(err)
 	}
 	return nil
 }
@@ -211,37 +409,168 @@ func (s *Session) Limit(num int) *Session {
 	return s
 }
 
+// Select sets the columns Find, First and Scan read, and the columns Insert
+// and Updates write even when blank, such as false or 0. It is either a
+// list of columns or, when query has ? placeholders, an expression and its
+// arguments, such as a subquery:
+//
+//	Select("name, ? AS orders", s.Table("orders").Select("count(*)").Where("orders.user_id = user.id"))
 func (s *Session) Select(query interface{}, values ...interface{}) *Session {
+	s.selectVars = nil
 	switch v := query.(type) {
 	case []string:
-		s.content.SelectFields = v
-		s.clause.Set(clause.SELECT, v)
+		s.selects = v
 	default:
+		desc := query.(string)
+		if tokens, err := lex(desc, s.dialect.Syntax()); err == nil && len(values) > 0 {
+			if params, _ := countParams(tokens); params > 0 {
+				s.selects, s.selectVars = []string{desc}, values
+				break
+			}
+		}
 		list := make([]string, 0, 1+len(values))
-		list = append(list, query.(string))
+		list = append(list, desc)
 		for _, value := range values {
 			list = append(list, value.(string))
 		}
-		s.content.SelectFields = list
-		s.clause.Set(clause.SELECT, list)
+		s.selects = list
 	}
+	s.setSelect(s.selects, s.selectVars...)
 	return s
 }
 
-func (s *Session) Table(desc string) *Session {
-	s.content.TableName = desc
-	s.clause.Set(clause.TABLE, desc)
+// Omit leaves columns out of Insert and Updates, named by column or field
+func (s *Session) Omit(columns ...string) *Session {
+	s.omits = append(s.omits, splitColumns(columns)...)
 	return s
 }
 
-func (s *Session) Where(desc string, args ...interface{}) *Session {
-	var vars []interface{}
-	sql, sqlVars := s.clause.Get(clause.WHERE)
-	if len(sql) > 0 {
-		desc = sql + " AND " + desc
-		args = append(sqlVars, args...)
+// writeSelects returns the columns Insert and Updates write even when blank
+func (s *Session) writeSelects() []string {
+	if len(s.selectVars) > 0 {
+		return nil
+	}
+	return splitColumns(s.selects)
+}
+
+// splitColumns splits the lists of columns such as "name, age"
+func splitColumns(columns []string) []string {
+	var names []string
+	for _, column := range columns {
+		for _, name := range strings.Split(column, ",") {
+			names = append(names, strings.TrimSpace(name))
+		}
+	}
+	return names
+}
+
+// Distinct selects the distinct rows, of columns if any
+func (s *Session) Distinct(columns ...string) *Session {
+	s.distinct = true
+	if len(columns) > 0 {
+		return s.Select(columns)
+	}
+	if len(s.selects) > 0 {
+		s.setSelect(s.selects, s.selectVars...)
+	}
+	return s
+}
+
+// setSelect sets the SELECT clause, or the DISTINCT one once Distinct is called
+func (s *Session) setSelect(columns []string, vars ...interface{}) {
+	values := append([]interface{}{columns}, vars...)
+	if s.distinct {
+		s.clause.Unset(clause.SELECT)
+		s.clause.Set(clause.DISTINCT, values...)
+		return
+	}
+	s.clause.Set(clause.SELECT, values...)
+}
+
+// selectSQL builds the SELECT statement of Find
+func (s *Session) selectSQL() (string, []interface{}) {
+	return s.clause.Build(clause.WITH, clause.DISTINCT, clause.SELECT, clause.TABLE, clause.JOIN, clause.WHERE,
+		clause.GROUPBY, clause.HAVING, clause.ORDERBY, clause.LIMIT, clause.OFFSET)
+}
+
+// from returns the table of the statement, a table set by Table is kept,
+// such as "user u" to join with an alias
+func (s *Session) from() string {
+	from, _ := s.clause.Get(clause.TABLE)
+	if from == "" {
+		from = s.content.TableName
+		s.clause.Set(clause.TABLE, from)
+	}
+	return from
+}
+
+// GroupBy groups the rows by desc, such as GroupBy("company_id, role")
+func (s *Session) GroupBy(desc string) *Session {
+	s.clause.Set(clause.GROUPBY, desc)
+	return s
+}
+
+// Having adds a condition on the groups joined with AND to the previous ones,
+// it takes the same arguments as Where
+func (s *Session) Having(query interface{}, args ...interface{}) *Session {
+	s.having = clause.And(s.having, s.condition(query, args))
+	if sql, vars := s.having.Build(); sql != "" {
+		s.clause.Set(clause.HAVING, append([]interface{}{sql}, vars...)...)
+	}
+	return s
+}
+
+// Table sets the table of the statement, such as "user u" to join with an
+// alias, or a derived table when desc has ? placeholders:
+//
+//	Table("? AS adults", s.Table("user").Where("age >= ?", 18))
+func (s *Session) Table(desc string, args ...interface{}) *Session {
+	desc, args = s.bindNamed(desc, args)
+	// a derived table binds its args in this statement only
+	if len(args) == 0 {
+		s.content.TableName = desc
+	}
+	s.clause.Set(clause.TABLE, append([]interface{}{desc}, args...)...)
+	return s
+}
+
+// Where adds a condition joined with AND to the previous ones, query is
+// either SQL with ? placeholders or a clause.Expression such as clause.Eq.
+// A single map or struct argument binds :name and @name parameters.
+func (s *Session) Where(query interface{}, args ...interface{}) *Session {
+	return s.setWhere(clause.And(s.where, s.condition(query, args)))
+}
+
+// Or adds a condition joined with OR to the previous ones, which are
+// parenthesized: Where(a).Where(b).Or(c) renders (a AND b) OR c
+func (s *Session) Or(query interface{}, args ...interface{}) *Session {
+	return s.setWhere(clause.Or(s.where, s.condition(query, args)))
+}
+
+// Not adds a negated condition joined with AND to the previous ones
+func (s *Session) Not(query interface{}, args ...interface{}) *Session {
+	return s.setWhere(clause.And(s.where, clause.Not(s.condition(query, args))))
+}
+
+// condition converts the arguments of Where, Or and Not to an expression
+func (s *Session) condition(query interface{}, args []interface{}) clause.Expression {
+	switch q := query.(type) {
+	case *Session:
+		return clause.Expr{SQL: "?", Vars: []interface{}{&bindError{msg: "a subquery is a value of a condition, such as Where(\"id IN ?\", subquery)"}}}
+	case clause.Expression:
+		return q
+	case string:
+		desc, vars := s.bindNamed(q, args)
+		return clause.Expr{SQL: desc, Vars: vars}
+	}
+	return clause.Expr{SQL: "?", Vars: []interface{}{&bindError{msg: fmt.Sprintf("unsupported condition %T", query)}}}
+}
+
+func (s *Session) setWhere(where clause.Expression) *Session {
+	s.where = where
+	if sql, vars := where.Build(); sql != "" {
+		s.clause.Set(clause.WHERE, append([]interface{}{sql}, vars...)...)
 	}
-	s.clause.Set(clause.WHERE, append(append(vars, desc), args...)...)
 	return s
 }
 
@@ -272,7 +601,7 @@ func (s *Session) Scan(values interface{}) error {
 	value := reflect.Indirect(reflect.ValueOf(values))
 	sql, sqlVars := s.clause.Build(clause.Operator...)
 	if sql != "" {
-		s.Raw(sql, sqlVars...)
+		s.raw(sql, sqlVars)
 	}
 	switch value.Kind() {
 	case reflect.Slice, reflect.Array:
@@ -283,16 +612,19 @@ func (s *Session) Scan(values interface{}) error {
 		dest := reflect.New(value.Type().Elem())
 		for rows.Next() {
 			if err = rows.Scan(dest.Interface()); err != nil {
-				return err
+				return s.wrapErr(err)
 			}
 			value.Set(reflect.Append(value, dest.Elem()))
 		}
+		if err = rows.Err(); err != nil {
+			return s.wrapErr(err)
+		}
 		if value.Len() == 0 {
 			return log.ErrRecordNotFound
 		}
 	default:
 		if err := s.QueryRow().Scan(value.Addr().Interface()); err != nil {
-			return err
+			return s.wrapErr(err)
 		}
 	}
 	return nil
@@ -0,0 +1,489 @@
+package session
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/catbugdemo/sorm/clause"
+	"github.com/catbugdemo/sorm/dialect"
+	"github.com/catbugdemo/sorm/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordUser struct {
+	Id   int64 `sorm:"primaryKey;autoIncrement"`
+	Name string
+	Age  int
+}
+
+func dryRunSession(name string) *Session {
+	dial, _ := dialect.GetDialect(name)
+	return New(nil, dial)
+}
+
+func TestToSQL(t *testing.T) {
+	s := dryRunSession("postgres")
+	sql, vars, err := s.ToSQL(func(tx *Session) error {
+		var users []recordUser
+		return tx.Where("age > ?", 18).Where("name IN (?)", []string{"a", "b"}).OrderBy("id").Limit(10).Find(&users)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id,name,age FROM record_user WHERE age > $1 AND name IN ($2,$3) ORDER BY id LIMIT $4", sql)
+	assert.Equal(t, []interface{}{18, "a", "b", 10}, vars)
+
+	sql, vars, err = s.ToSQL(func(tx *Session) error {
+		return tx.Model(&recordUser{}).Where("id = ?", 1).Update("name", "x")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "UPDATE record_user SET name=$1 WHERE id = $2", sql)
+	assert.Equal(t, []interface{}{"x", 1}, vars)
+
+	sql, vars, err = dryRunSession("mysql").ToSQL(func(tx *Session) error {
+		return tx.Insert(&[]recordUser{{Name: "a", Age: 1}, {Name: "b", Age: 2}})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT INTO record_user(name,age) VALUES (?,?),(?,?)", sql)
+	assert.Equal(t, []interface{}{"a", 1, "b", 2}, vars)
+}
+
+func TestFillInsertIds(t *testing.T) {
+	dial, _ := dialect.GetDialect("mysql")
+	table := New(nil, dial).Model(&recordUser{}).RefTable()
+	users := []recordUser{{Name: "a"}, {Id: 10, Name: "b"}, {Name: "c"}, {Name: "d"}}
+	fillInsertIds(table.PrimaryField(), reflect.ValueOf(users), 5)
+	assert.Equal(t, []int64{5, 10, 6, 7}, []int64{users[0].Id, users[1].Id, users[2].Id, users[3].Id})
+}
+
+func TestDryRun(t *testing.T) {
+	s := dryRunSession("sqlite3").DryRun()
+	_, err := s.Raw("DELETE FROM record_user WHERE id = ?", 1).Exec()
+	assert.ErrorIs(t, err, log.ErrDryRun)
+	assert.Equal(t, []Statement{{SQL: "DELETE FROM record_user WHERE id = ?", Vars: []interface{}{1}}}, s.Statements())
+
+	// the pending clauses of the session are left for it
+	s = dryRunSession("sqlite3").Where("age > ?", 1)
+	_, _, err = s.ToSQL(func(tx *Session) error {
+		return tx.Where("name = ?", "a").Find(&[]recordUser{})
+	})
+	assert.Nil(t, err)
+	sql, _ := s.clause.Get(clause.WHERE)
+	assert.Equal(t, "WHERE age > ?", sql)
+}
+
+// recordSession returns a sqlite session with the record tables, users 1 to
+// 4 and companies 1 and 2
+func recordSession(t *testing.T) *Session {
+	s := sqliteSession(t)
+	_, err := s.AutoMigrate(&recordUser{}, &recordCompany{}, &recordCategory{}, &recordMember{})
+	assert.Nil(t, err)
+	assert.Nil(t, s.Insert(&[]recordUser{{Id: 1, Name: "a", Age: 18}, {Id: 2, Name: "b", Age: 30}, {Id: 3, Name: "c", Age: 30}, {Id: 4, Name: "d", Age: 60}}))
+	assert.Nil(t, s.Insert(&[]recordCompany{{Id: 1, Name: "x"}, {Id: 2, Name: "acme"}}))
+	return s
+}
+
+// recordIds returns the ids of users
+func recordIds(users []recordUser) []int64 {
+	ids := make([]int64, len(users))
+	for i, user := range users {
+		ids[i] = user.Id
+	}
+	return ids
+}
+
+func TestWhereExpression(t *testing.T) {
+	sql, vars, err := dryRunSession("postgres").ToSQL(func(tx *Session) error {
+		return tx.Where("age > ?", 18).Where(clause.Eq{"name": []string{"a", "b"}}).Or("age IS NULL").Not(clause.Like("name", "x%")).Find(&[]recordUser{})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id,name,age FROM record_user WHERE ((age > $1 AND name IN ($2,$3)) OR age IS NULL) AND NOT (name LIKE $4 ESCAPE '!')", sql)
+	assert.Equal(t, []interface{}{18, "a", "b", "x%"}, vars)
+
+	s := recordSession(t)
+	var users []recordUser
+	assert.Nil(t, s.Where("age > ?", 18).Where(clause.Eq{"name": []string{"a", "b", "d"}}).Or("name = ?", "c").Not(clause.Like("name", "d%")).OrderBy("id").Find(&users))
+	assert.Equal(t, []int64{2, 3}, recordIds(users))
+
+	// the OR of a raw condition stays inside it
+	assert.Nil(t, s.Model(&recordUser{}).Where("age > ? OR age < ?", 50, 20).Where("name = ?", "a").Delete())
+	var count int64
+	assert.Nil(t, s.Model(&recordUser{}).Count(&count))
+	assert.Equal(t, int64(3), count)
+}
+
+type recordCompany struct {
+	Id   int64 `sorm:"primaryKey"`
+	Name string
+}
+
+type recordUserCompany struct {
+	User    recordUser    `sorm:"embedded"`
+	Company recordCompany `sorm:"embedded;prefix:company_"`
+}
+
+func TestJoins(t *testing.T) {
+	sql, vars, err := dryRunSession("postgres").ToSQL(func(tx *Session) error {
+		return tx.Table("record_user").InnerJoin(&recordCompany{}, "record_company.id = record_user.id").Where("record_user.name = ?", "a").Find(&[]recordUserCompany{})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT record_user.id,record_user.name,record_user.age,record_company.id AS company_id,record_company.name AS company_name "+
+		"FROM record_user INNER JOIN record_company ON record_company.id = record_user.id WHERE record_user.name = $1", sql)
+	assert.Equal(t, []interface{}{"a"}, vars)
+
+	s := recordSession(t)
+	var joined []recordUserCompany
+	assert.Nil(t, s.Table("record_user").InnerJoin(&recordCompany{}, "record_company.id = record_user.id").OrderBy("record_user.id").Find(&joined))
+	assert.Equal(t, []recordUserCompany{
+		{User: recordUser{Id: 1, Name: "a", Age: 18}, Company: recordCompany{Id: 1, Name: "x"}},
+		{User: recordUser{Id: 2, Name: "b", Age: 30}, Company: recordCompany{Id: 2, Name: "acme"}},
+	}, joined)
+
+	var users []recordUser
+	assert.Nil(t, s.Table("record_user u").Joins("LEFT JOIN record_company c ON c.id = u.id AND c.name <> ?", "x").Where("c.id IS NULL").OrderBy("u.id").Find(&users))
+	assert.Equal(t, []int64{1, 3, 4}, recordIds(users))
+}
+
+func TestGroupBy(t *testing.T) {
+	sql, vars, err := dryRunSession("postgres").ToSQL(func(tx *Session) error {
+		return tx.Model(&recordUser{}).Select("age", "count(*)").Where("name <> ?", "a").
+			GroupBy("age").Having("count(*) > ?", 1).Having("age < ?", 60).Find(&[]recordUser{})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT age,count(*) FROM record_user WHERE name <> $1 GROUP BY age HAVING count(*) > $2 AND age < $3", sql)
+	assert.Equal(t, []interface{}{"a", 1, 60}, vars)
+
+	// postgres and mysql reject selecting * from a grouped query
+	sql, _, err = dryRunSession("postgres").ToSQL(func(tx *Session) error {
+		var count int64
+		return tx.Model(&recordUser{}).GroupBy("age, name").Count(&count)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT count(*) FROM (SELECT age, name FROM record_user GROUP BY age, name) sorm_count", sql)
+
+	s := recordSession(t)
+	var groups []recordUser
+	assert.Nil(t, s.Model(&recordUser{}).Select("age", "count(*) AS id").Where("name <> ?", "a").
+		GroupBy("age").Having("count(*) > ?", 1).Having("age < ?", 60).Find(&groups))
+	assert.Equal(t, []recordUser{{Id: 2, Age: 30}}, groups)
+
+	var count int64
+	assert.Nil(t, s.Model(&recordUser{}).GroupBy("age").Count(&count))
+	assert.Equal(t, int64(3), count)
+	assert.Nil(t, s.Model(&recordUser{}).Distinct().GroupBy("age").Having("count(*) > ?", 1).Count(&count))
+	assert.Equal(t, int64(1), count)
+}
+
+func TestDistinct(t *testing.T) {
+	sql, _, err := dryRunSession("sqlite3").ToSQL(func(tx *Session) error {
+		var count int64
+		return tx.Model(&recordUser{}).Distinct("name").Count(&count)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT count(*) FROM (SELECT DISTINCT name FROM record_user) sorm_count", sql)
+
+	s := recordSession(t)
+	var users []recordUser
+	assert.Nil(t, s.Model(&recordUser{}).Distinct("age").OrderBy("age").Find(&users))
+	assert.Equal(t, []recordUser{{Age: 18}, {Age: 30}, {Age: 60}}, users)
+
+	var count int64
+	assert.Nil(t, s.Model(&recordUser{}).Distinct("age").Count(&count))
+	assert.Equal(t, int64(3), count)
+}
+
+func TestAggregate(t *testing.T) {
+	sql, vars, err := dryRunSession("postgres").ToSQL(func(tx *Session) error {
+		var sum *int64
+		return tx.Model(&recordUser{}).Where("name = ?", "a").Sum("age", &sum)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT sum(age) FROM record_user WHERE name = $1", sql)
+	assert.Equal(t, []interface{}{"a"}, vars)
+
+	s := recordSession(t)
+	var sum, max int64
+	assert.Nil(t, s.Model(&recordUser{}).Where("age = ?", 30).Sum("age", &sum))
+	assert.Equal(t, int64(60), sum)
+	assert.Nil(t, s.Model(&recordUser{}).Max("age", &max))
+	assert.Equal(t, int64(60), max)
+
+	// the sum of no rows is NULL
+	none := new(int64)
+	assert.Nil(t, s.Model(&recordUser{}).Where("age > ?", 100).Sum("age", &none))
+	assert.Nil(t, none)
+
+	var names []string
+	assert.Nil(t, s.Table("record_user").Where("age = ?", 30).OrderBy("name").Pluck("name", &names))
+	assert.Equal(t, []string{"b", "c"}, names)
+	for _, dest := range []interface{}{names, new(string), (*[]string)(nil), nil} {
+		assert.True(t, errors.Is(s.Where("age = ?", 30).Pluck("name", dest), log.ErrValuesNotPointer), "%T", dest)
+	}
+	// the statement is dropped with its conditions
+	names = nil
+	assert.Nil(t, s.Table("record_user").Pluck("name", &names))
+	assert.Equal(t, 4, len(names))
+}
+
+func TestSubquery(t *testing.T) {
+	sql, vars, err := dryRunSession("postgres").ToSQL(func(tx *Session) error {
+		count := dryRunSession("postgres").Table("record_company c").Select("count(*)").Where("c.id = u.id AND c.name <> ?", "x")
+		adults := dryRunSession("postgres").Model(&recordUser{}).Where("age >= ?", 18)
+		return tx.Select("u.name, ? AS age", count).Table("? AS u", adults).Where(clause.In("u.id", dryRunSession("postgres").Raw("SELECT id FROM vip WHERE level > ?", 2))).Find(&[]recordUser{})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT u.name, (SELECT count(*) FROM record_company c WHERE c.id = u.id AND c.name <> $1) AS age "+
+		"FROM (SELECT id,name,age FROM record_user WHERE age >= $2) AS u WHERE u.id IN (SELECT id FROM vip WHERE level > $3)", sql)
+	assert.Equal(t, []interface{}{"x", 18, 2}, vars)
+
+	s := recordSession(t)
+	companies := New(s.db, s.dialect).Table("record_company").Select("id").Where("name = ?", "acme")
+	_, _, err = s.ToSQL(func(tx *Session) error {
+		return tx.Where(companies).Find(&[]recordUser{})
+	})
+	assert.NotNil(t, err)
+
+	var users []recordUser
+	assert.Nil(t, s.Where("age > ?", 18).Where("id IN ?", companies).Find(&users))
+	assert.Equal(t, []int64{2}, recordIds(users))
+
+	users = nil
+	count := New(s.db, s.dialect).Table("record_company c").Select("count(*)").Where("c.id = u.id AND c.name <> ?", "x")
+	adults := New(s.db, s.dialect).Model(&recordUser{}).Where("age >= ?", 30)
+	assert.Nil(t, s.Select("u.name, ? AS age", count).Table("? AS u", adults).Where(clause.In("u.id", []int{2, 3})).OrderBy("u.id").Find(&users))
+	assert.Equal(t, []recordUser{{Name: "b", Age: 1}, {Name: "c", Age: 0}}, users)
+
+	// the derived table is the table of that statement only
+	users = nil
+	assert.Nil(t, s.Find(&users))
+	assert.Equal(t, []int64{1, 2, 3, 4}, recordIds(users))
+}
+
+type recordCategory struct {
+	Id       int64 `sorm:"primaryKey"`
+	ParentId *int64
+	Name     string
+}
+
+func TestWith(t *testing.T) {
+	sql, vars, err := dryRunSession("postgres").ToSQL(func(tx *Session) error {
+		adults := dryRunSession("postgres").Model(&recordUser{}).Where("age >= ?", 18)
+		return tx.With("adults", adults).With("named(name)", "SELECT name FROM record_company WHERE id = ?", 2).
+			Table("adults").Where("name IN (SELECT name FROM named) AND age < ?", 60).Find(&[]recordUser{})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "WITH adults AS (SELECT id,name,age FROM record_user WHERE age >= $1), named(name) AS (SELECT name FROM record_company WHERE id = $2) "+
+		"SELECT id,name,age FROM adults WHERE name IN (SELECT name FROM named) AND age < $3", sql)
+	assert.Equal(t, []interface{}{18, 2, 60}, vars)
+
+	// the expressions of the session come before the ones given to ToSQL
+	sql, _, err = dryRunSession("sqlite3").With("a", "SELECT 1").ToSQL(func(tx *Session) error {
+		return tx.With("b", "SELECT 2").Table("a").Find(&[]recordUser{})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "WITH a AS (SELECT 1), b AS (SELECT 2) SELECT id,name,age FROM a", sql)
+
+	s := recordSession(t)
+	var users []recordUser
+	adults := New(s.db, s.dialect).Model(&recordUser{}).Where("age >= ?", 30)
+	assert.Nil(t, s.With("adults", adults).With("named(name)", "SELECT name FROM record_user WHERE id IN (?)", []int{3, 4}).
+		Table("adults").Where("name IN (SELECT name FROM named) AND age < ?", 60).Find(&users))
+	assert.Equal(t, []int64{3}, recordIds(users))
+
+	// the tree under 1, the root included
+	one, two := int64(1), int64(2)
+	assert.Nil(t, s.Insert(&[]recordCategory{{Id: 1, Name: "root"}, {Id: 2, ParentId: &one, Name: "b"}, {Id: 3, ParentId: &two, Name: "a"}, {Id: 4, Name: "other"}}))
+	var categories []recordCategory
+	assert.Nil(t, s.OrderBy("name").Descendants(&categories, "parent_id", 1))
+	assert.Equal(t, []recordCategory{{Id: 3, ParentId: &two, Name: "a"}, {Id: 2, ParentId: &one, Name: "b"}, {Id: 1, Name: "root"}}, categories)
+
+	// the next statements read the table of the model again
+	categories = nil
+	assert.Nil(t, s.Find(&categories))
+	assert.Equal(t, 4, len(categories))
+}
+
+func TestOnConflict(t *testing.T) {
+	users := []recordUser{{Id: 1, Name: "a", Age: 18}, {Id: 2, Name: "b", Age: 20}}
+	sql, vars, err := dryRunSession("postgres").ToSQL(func(tx *Session) error {
+		return tx.OnConflict("id").UpdateAll().Insert(&users)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT INTO record_user(id,name,age) VALUES ($1,$2,$3),($4,$5,$6) "+
+		"ON CONFLICT (id) DO UPDATE SET name = excluded.name,age = excluded.age RETURNING id,name,age", sql)
+	assert.Equal(t, []interface{}{int64(1), "a", 18, int64(2), "b", 20}, vars)
+
+	sql, _, err = dryRunSession("mysql").ToSQL(func(tx *Session) error {
+		return tx.OnConflict().DoNothing().Insert(&users)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT INTO record_user(id,name,age) VALUES (?,?,?),(?,?,?) ON DUPLICATE KEY UPDATE id = id", sql)
+
+	// the conflict handling of the session applies to the insert of ToSQL
+	sql, _, err = dryRunSession("sqlite3").OnConflict("id").DoNothing().ToSQL(func(tx *Session) error {
+		return tx.Insert(&users)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT INTO record_user(id,name,age) VALUES (?,?,?),(?,?,?) ON CONFLICT (id) DO NOTHING RETURNING id,name,age", sql)
+
+	s := recordSession(t)
+	upserted := []recordUser{{Id: 1, Name: "a2", Age: 19}, {Id: 5, Name: "e", Age: 50}}
+	assert.Nil(t, s.OnConflict("id").UpdateAll().Insert(&upserted))
+	assert.Equal(t, []recordUser{{Id: 1, Name: "a2", Age: 19}, {Id: 5, Name: "e", Age: 50}}, upserted)
+
+	// the rows left as they are by DoNothing are not written back
+	assert.Nil(t, s.OnConflict().DoNothing().Insert(&[]recordUser{{Id: 2, Name: "b2", Age: 1}}))
+	assert.Nil(t, s.OnConflict().DoUpdate("name").Insert(&[]recordUser{{Id: 3, Name: "c2", Age: 1}}))
+	var stored []recordUser
+	assert.Nil(t, s.OrderBy("id").Find(&stored))
+	assert.Equal(t, []recordUser{{Id: 1, Name: "a2", Age: 19}, {Id: 2, Name: "b", Age: 30}, {Id: 3, Name: "c2", Age: 30}, {Id: 4, Name: "d", Age: 60}, {Id: 5, Name: "e", Age: 50}}, stored)
+}
+
+func TestInsertBatches(t *testing.T) {
+	users := make([]recordUser, 1000)
+	for i := range users {
+		users[i] = recordUser{Id: int64(i + 1), Name: "a", Age: i}
+	}
+	// the zero age of the first user is left out of its insert
+	batches := dryRunSession("sqlite3").insertBatches(reflect.ValueOf(users), 0)
+	assert.Equal(t, 4, len(batches))
+	assert.Equal(t, []string{"id", "name"}, batches[0].columns)
+	assert.Equal(t, 1, batches[0].rows.Len())
+	assert.Equal(t, []int{333, 333, 333}, []int{batches[1].rows.Len(), batches[2].rows.Len(), batches[3].rows.Len()})
+	assert.Equal(t, int64(335), batches[2].rows.Index(0).Interface().(recordUser).Id)
+
+	batches = dryRunSession("postgres").insertBatches(reflect.ValueOf(users[1:]), 400)
+	assert.Equal(t, 3, len(batches))
+	assert.Equal(t, 199, batches[2].rows.Len())
+	assert.Equal(t, 199, len(batches[2].values))
+}
+
+func TestInsertChunks(t *testing.T) {
+	s := sqliteSession(t)
+	_, err := s.AutoMigrate(&recordUser{})
+	assert.Nil(t, err)
+	// 1200 rows of 2 columns bind more than the 999 vars of sqlite
+	newUsers := func(name string) []recordUser {
+		users := make([]recordUser, 1200)
+		for i := range users {
+			users[i] = recordUser{Name: name, Age: i + 1}
+		}
+		return users
+	}
+	users := newUsers("a")
+	assert.Nil(t, s.Insert(&users))
+	for i, user := range users {
+		if !assert.Equal(t, recordUser{Id: int64(i + 1), Name: "a", Age: i + 1}, user) {
+			break
+		}
+	}
+
+	// the chunks are inserted in one transaction, rolled back by a failing chunk
+	failing := newUsers("b")
+	failing[1100].Id = 1
+	assert.NotNil(t, s.Insert(&failing))
+	var count int64
+	assert.Nil(t, s.Model(&recordUser{}).Count(&count))
+	assert.Equal(t, int64(1200), count)
+
+	// an array is inserted like a slice, written back through a pointer
+	array := [2]recordUser{{Name: "d", Age: 1}, {Name: "d", Age: 2}}
+	assert.Nil(t, s.Insert(array))
+	assert.Nil(t, s.Insert(&array))
+	assert.Equal(t, [2]int64{1203, 1204}, [2]int64{array[0].Id, array[1].Id})
+	assert.Nil(t, s.Model(&recordUser{}).Where("name = ?", "d").Count(&count))
+	assert.Equal(t, int64(4), count)
+
+	// the chunks join the transaction of the session
+	tx := New(s.db, s.dialect)
+	assert.Nil(t, tx.Begin())
+	users = newUsers("c")
+	assert.Nil(t, tx.Insert(&users))
+	assert.Equal(t, int64(2404), users[1199].Id)
+	assert.Nil(t, tx.Rollback())
+	assert.Nil(t, s.Model(&recordUser{}).Count(&count))
+	assert.Equal(t, int64(1204), count)
+}
+
+func TestSelectOmit(t *testing.T) {
+	sql, vars, err := dryRunSession("sqlite3").ToSQL(func(tx *Session) error {
+		return tx.Model(&recordUser{}).Select("age").Omit("id").Where("id = ?", 1).Updates(&recordUser{Id: 1, Name: "a"})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "UPDATE record_user SET age=?,name=? WHERE id = ?", sql)
+	assert.Equal(t, []interface{}{0, "a", 1}, vars)
+
+	sql, vars, err = dryRunSession("sqlite3").ToSQL(func(tx *Session) error {
+		return tx.Model(&recordUser{}).Select("name, age").Omit("id").Where("id = ?", 1).Updates(&recordUser{Id: 1})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "UPDATE record_user SET age=?,name=? WHERE id = ?", sql)
+	assert.Equal(t, []interface{}{0, "", 1}, vars)
+
+	s := recordSession(t)
+	// the selected zero values are inserted instead of the column defaults
+	inserted := []recordUser{{Id: 5}}
+	assert.Nil(t, s.Select("name", "Age").Insert(&inserted))
+	assert.Nil(t, s.Model(&recordUser{}).Select("age").Where("id = ?", 1).Updates(&recordUser{Name: "a2"}))
+	assert.Nil(t, s.Model(&recordUser{}).Omit("name").Where("id = ?", 2).Updates(map[string]interface{}{"name": "b2", "age": 31}))
+	assert.Nil(t, s.Model(&recordUser{}).Select("name, age").Where("id = ?", 3).Updates(&recordUser{}))
+	var users []recordUser
+	assert.Nil(t, s.Where("id IN (?)", []int{1, 2, 3, 5}).OrderBy("id").Find(&users))
+	assert.Equal(t, []recordUser{{Id: 1, Name: "a2", Age: 0}, {Id: 2, Name: "b", Age: 31}, {Id: 3}, {Id: 5}}, users)
+}
+
+func TestPrimaryKey(t *testing.T) {
+	sql, vars, err := dryRunSession("postgres").ToSQL(func(tx *Session) error {
+		return tx.Save(&recordUser{Id: 2, Name: "a"})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT INTO record_user(id,name,age) VALUES ($1,$2,$3) ON CONFLICT (id) DO UPDATE SET name = excluded.name,age = excluded.age RETURNING id,name,age", sql)
+	assert.Equal(t, []interface{}{int64(2), "a", 0}, vars)
+
+	s := recordSession(t)
+	var user recordUser
+	assert.Nil(t, s.Get(&user, 3))
+	assert.Equal(t, recordUser{Id: 3, Name: "c", Age: 30}, user)
+	assert.True(t, errors.Is(s.Get(&user, 9), log.ErrRecordNotFound))
+
+	// a blank key is inserted, any other updated
+	created := recordUser{Name: "e", Age: 40}
+	assert.Nil(t, s.Save(&created))
+	assert.Equal(t, int64(5), created.Id)
+	assert.Nil(t, s.Save(&recordUser{Id: 3, Name: "c2"}))
+	assert.Nil(t, s.Reload(&user))
+	assert.Equal(t, recordUser{Id: 3, Name: "c2", Age: 0}, user)
+	assert.True(t, errors.Is(s.Reload(&recordUser{Name: "a"}), log.ErrMissingPrimaryKey))
+
+	assert.Nil(t, s.DeleteModel(&recordUser{Id: 2}))
+	var users []recordUser
+	assert.Nil(t, s.Get(&users, 1, 2, 3))
+	assert.Equal(t, []int64{1, 3}, recordIds(users))
+}
+
+type recordMember struct {
+	TeamId int64 `sorm:"primaryKey"`
+	UserId int64 `sorm:"primaryKey"`
+	Role   string
+}
+
+func TestCompositePrimaryKey(t *testing.T) {
+	sql, vars, err := dryRunSession("postgres").ToSQL(func(tx *Session) error {
+		return tx.Get(&[]recordMember{}, []interface{}{1, 2}, []interface{}{1, 3})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT team_id,user_id,role FROM record_member WHERE (team_id,user_id) IN (($1,$2),($3,$4))", sql)
+	assert.Equal(t, []interface{}{1, 2, 1, 3}, vars)
+
+	s := recordSession(t)
+	assert.Nil(t, s.Insert(&[]recordMember{{TeamId: 1, UserId: 2, Role: "owner"}, {TeamId: 1, UserId: 3, Role: "member"}, {TeamId: 2, UserId: 2, Role: "member"}}))
+	var member recordMember
+	assert.Nil(t, s.Get(&member, 1, 2))
+	assert.Equal(t, recordMember{TeamId: 1, UserId: 2, Role: "owner"}, member)
+	assert.True(t, errors.Is(s.Get(&member, 1), log.ErrMissingPrimaryKey))
+
+	assert.Nil(t, s.Save(&recordMember{TeamId: 2, UserId: 2, Role: "owner"}))
+	assert.Nil(t, s.DeleteModel(&[]recordMember{{TeamId: 1, UserId: 2}, {TeamId: 1, UserId: 3}}))
+	var members []recordMember
+	assert.Nil(t, s.Get(&members, []interface{}{1, 2}, []interface{}{2, 2}))
+	assert.Equal(t, []recordMember{{TeamId: 2, UserId: 2, Role: "owner"}}, members)
+}
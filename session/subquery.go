@@ -0,0 +1,35 @@
+package session
+
+import (
+	"strings"
+
+	"github.com/catbugdemo/sorm/clause"
+)
+
+var _ clause.Expression = (*Session)(nil)
+
+// Build returns the statement of the session with ? placeholders, so a
+// session is a clause.Expression bound as a subquery by Where, Table and
+// Select, its arguments merged in order with the ones of the outer query:
+//
+//	s.Model(&User{}).Where("id IN ?", db.NewSession().Table("orders").Select("user_id").Where("amount > ?", 100))
+//
+// A session built with Raw returns its statement, else the SELECT of its
+// clauses, every column of the model unless Select is called. The session
+// itself is left unchanged.
+func (s *Session) Build() (string, []interface{}) {
+	if s.sql.Len() > 0 {
+		return strings.TrimSpace(s.sql.String()), s.sqlVars
+	}
+	sub := s.clone()
+	sub.from()
+	switch {
+	case len(sub.selects) > 0:
+		sub.setSelect(sub.selects, sub.selectVars...)
+	case len(sub.content.SelectFields) > 0:
+		sub.setSelect(sub.content.SelectFields)
+	default:
+		sub.setSelect([]string{"*"})
+	}
+	return sub.selectSQL()
+}
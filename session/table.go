@@ -1,6 +1,7 @@
 package session
 
 import (
+	"database/sql"
 	"fmt"
 	"github.com/catbugdemo/sorm/log"
 	"github.com/catbugdemo/sorm/schema"
@@ -8,15 +9,42 @@ import (
 	"strings"
 )
 
+// Model sets the model of the statement, an invalid model is reported by the
+// statement, see modelTable
 func (s *Session) Model(value interface{}) *Session {
-	if s.refTable == nil || reflect.TypeOf(value).Name() != s.RefTable().Name {
-		s.refTable = schema.Parse(value, s.dialect)
+	// the schema of an invalid model has no field, it is parsed again
+	if s.refTable == nil || reflect.TypeOf(value).Name() != s.RefTable().Name || len(s.refTable.Fields) == 0 {
+		s.refTable = s.parse(value)
 		s.content = Generate(s.RefTable().FieldNames, s.RefTable().SqlName)
 	}
 
 	return s
 }
 
+// parse parses value with the naming strategy of the session, the error of
+// an invalid model is kept for the statement, which fails once executed, and
+// an empty schema returned in its place
+func (s *Session) parse(value interface{}) *schema.Schema {
+	table, err := schema.ParseWithNamer(value, s.dialect, s.namer)
+	if err != nil {
+		log.Error(err)
+		s.err = err
+		return &schema.Schema{Model: value, FieldSqlMap: map[string]string{}}
+	}
+	return table
+}
+
+// modelTable sets the model of the statement to value and returns its
+// schema, or the error of an invalid model
+func (s *Session) modelTable(value interface{}) (*schema.Schema, error) {
+	table := s.Model(value).RefTable()
+	if err := s.err; err != nil {
+		s.Clear()
+		return nil, err
+	}
+	return table, nil
+}
+
 func (s *Session) RefTable() *schema.Schema {
 	if s.refTable == nil {
 		log.Error("Model is not set")
@@ -24,29 +52,118 @@ func (s *Session) RefTable() *schema.Schema {
 	return s.refTable
 }
 
+// CreateTable creates the table of the model and the indexes of its tags
 func (s *Session) CreateTable() error {
-	table := s.RefTable()
+	if _, err := s.raw(createTableSQL(s.RefTable()), nil).Exec(); err != nil {
+		return err
+	}
+	return s.CreateIndexes()
+}
+
+// createTableSQL renders the CREATE TABLE statement of table, a composite
+// primary key is a table constraint following the columns
+func createTableSQL(table *schema.Schema) string {
+	composite := compositeKey(table)
 	var columns []string
 	for _, field := range table.Fields {
-		columns = append(columns, fmt.Sprintf("%s %s %s", field.SqlName, field.Type, field.Tag))
+		columns = append(columns, columnDefinition(field, composite))
+	}
+	if composite {
+		columns = append(columns, primaryKeyConstraint(table))
 	}
 	desc := strings.Join(columns, ",")
-	_, err := s.Raw(fmt.Sprintf("CREATE TABLE %s (%s)", table.SqlName, desc)).Exec()
-	return err
+	return fmt.Sprintf("CREATE TABLE %s (%s)", table.SqlName, desc)
+}
+
+// compositeKey reports whether several fields make the primary key of table
+func compositeKey(table *schema.Schema) bool {
+	return len(table.PrimaryFields) > 1
+}
+
+// primaryKeyConstraint renders PRIMARY KEY (a,b) of a composite primary key
+func primaryKeyConstraint(table *schema.Schema) string {
+	columns := make([]string, 0, len(table.PrimaryFields))
+	for _, field := range table.PrimaryFields {
+		columns = append(columns, field.SqlName)
+	}
+	return fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(columns, ","))
+}
+
+// columnDefinition renders the column of field from its tag properties,
+// the column type already carries the size and auto increment of the dialect.
+// The columns of a composite primary key are NOT NULL, the key is declared
+// by the table.
+func columnDefinition(field *schema.Field, composite bool) string {
+	column := []string{field.SqlName, field.Type}
+	primary := field.PrimaryKey && !composite
+	if primary {
+		column = append(column, "PRIMARY KEY")
+	}
+	if field.NotNull || !field.Nullable && !primary {
+		column = append(column, "NOT NULL")
+	}
+	if field.Unique && !field.PrimaryKey {
+		column = append(column, "UNIQUE")
+	}
+	if field.Default != "" {
+		column = append(column, "DEFAULT "+field.Default)
+	}
+	return strings.Join(column, " ")
 }
 
 func (s *Session) DropTable() error {
-	_, err := s.Raw(fmt.Sprintf("DROP TABLE IF EXISTS %s;", s.refTable.SqlName)).Exec()
+	_, err := s.raw(fmt.Sprintf("DROP TABLE IF EXISTS %s;", s.refTable.SqlName), nil).Exec()
 	return err
 }
 
 func (s *Session) HasTable() bool {
-	sql, values := s.dialect.TableExistSQL(s.RefTable().SqlName)
-	rows := s.Raw(sql, values...).QueryRow()
+	query, values := s.dialect.TableExistSQL(s.RefTable().SqlName)
+	rows := s.raw(query, values).QueryRow()
 	var tmp string
 	if err := rows.Scan(&tmp); err != nil {
-		log.Error(err)
+		if err != sql.ErrNoRows {
+			log.Error(s.wrapErr(err))
+		}
 		return false
 	}
 	return tmp == s.RefTable().SqlName
 }
+
+// CreateIndexes creates the indexes declared by the model tags that are missing
+func (s *Session) CreateIndexes() error {
+	table := s.RefTable()
+	for _, index := range table.Indexes {
+		if s.HasIndex(index.Name) {
+			continue
+		}
+		query, err := s.dialect.CreateIndexSQL(index.Dialect(table.SqlName))
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		if _, err = s.raw(query, nil).Exec(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DropIndex drops the index name of the model table
+func (s *Session) DropIndex(name string) error {
+	_, err := s.raw(s.dialect.DropIndexSQL(s.RefTable().SqlName, name), nil).Exec()
+	return err
+}
+
+// HasIndex reports whether the model table has the index name
+func (s *Session) HasIndex(name string) bool {
+	query, values := s.dialect.IndexExistSQL(s.RefTable().SqlName, name)
+	row := s.raw(query, values).QueryRow()
+	var tmp string
+	if err := row.Scan(&tmp); err != nil {
+		if err != sql.ErrNoRows {
+			log.Error(s.wrapErr(err))
+		}
+		return false
+	}
+	return tmp == name
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/catbugdemo/sorm/dialect"
+	"github.com/catbugdemo/sorm/log"
+	"github.com/catbugdemo/sorm/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+type tableUser struct {
+	Id     int64   `sorm:"primaryKey;autoIncrement"`
+	Name   string  `sorm:"size:64;unique"`
+	Status string  `sorm:"default:'active'"`
+	Note   *string `sorm:"notNull"`
+	Email  *string
+}
+
+func TestCreateTableSQL(t *testing.T) {
+	for name, want := range map[string]string{
+		"postgres": "CREATE TABLE table_user (id bigserial PRIMARY KEY,name varchar(64) NOT NULL UNIQUE,status varchar NOT NULL DEFAULT 'active',note varchar NOT NULL,email varchar)",
+		"sqlite3":  "CREATE TABLE table_user (id integer PRIMARY KEY,name text NOT NULL UNIQUE,status text NOT NULL DEFAULT 'active',note text NOT NULL,email text)",
+		"mysql":    "CREATE TABLE table_user (id bigint AUTO_INCREMENT PRIMARY KEY,name varchar(64) NOT NULL UNIQUE,status varchar(255) NOT NULL DEFAULT 'active',note varchar(255) NOT NULL,email varchar(255))",
+	} {
+		dial, _ := dialect.GetDialect(name)
+		assert.Equal(t, want, createTableSQL(schema.Parse(&tableUser{}, dial)), name)
+	}
+}
+
+type tableMember struct {
+	TeamId int64 `sorm:"primaryKey"`
+	UserId int64 `sorm:"primaryKey"`
+	Role   string
+}
+
+func TestCreateTableCompositeKey(t *testing.T) {
+	dial, _ := dialect.GetDialect("sqlite3")
+	table := schema.Parse(&tableMember{}, dial)
+	assert.Equal(t, "CREATE TABLE table_member (team_id bigint NOT NULL,user_id bigint NOT NULL,role text NOT NULL,PRIMARY KEY (team_id,user_id))", createTableSQL(table))
+	assert.Equal(t, "team_id bigint NOT NULL", rebuildDefinition(table.GetField("TeamId"), &column{dataType: "bigint", notNull: true}, true))
+}
+
+func TestRebuildDefinition(t *testing.T) {
+	dial, _ := dialect.GetDialect("sqlite3")
+	table := schema.Parse(&tableUser{}, dial)
+	assert.Equal(t, "id INT PRIMARY KEY", rebuildDefinition(table.GetField("Id"), &column{dataType: "INT"}, false))
+	assert.Equal(t, "name varchar(64) UNIQUE", rebuildDefinition(table.GetField("Name"), &column{dataType: "varchar(64)"}, false))
+	assert.Equal(t, "status text NOT NULL DEFAULT 'active'", rebuildDefinition(table.GetField("Status"), &column{dataType: "text", notNull: true}, false))
+
+	index := definition{name: "idx_age", sql: "CREATE INDEX idx_age ON age_user (age) WHERE page > 0"}
+	name, ok := index.uses([]string{"page", "age"})
+	assert.True(t, ok)
+	assert.Equal(t, "page", name)
+	_, ok = index.uses([]string{"user", "ag"})
+	assert.False(t, ok)
+}
+
+type tableInvalid struct {
+	Id   int64  `sorm:"primaryKey"`
+	Name string `sorm:"size:abc"`
+}
+
+func TestInvalidModel(t *testing.T) {
+	s := sqliteSession(t)
+	_, err := s.AutoMigrate(&tableInvalid{})
+	assert.ErrorIs(t, err, log.ErrInvalidModel)
+	assert.ErrorIs(t, s.Model(&tableInvalid{}).CreateTable(), log.ErrInvalidModel)
+	assert.ErrorIs(t, s.Find(&[]tableInvalid{}), log.ErrInvalidModel)
+	assert.ErrorIs(t, s.Get(&tableInvalid{}, 1), log.ErrInvalidModel)
+
+	// the error fails a single statement
+	_, err = s.AutoMigrate(&tableUser{})
+	assert.Nil(t, err)
+	var count int
+	assert.Nil(t, s.Model(&tableUser{}).Count(&count))
+}
@@ -4,7 +4,8 @@ import "github.com/catbugdemo/sorm/log"
 
 func (s *Session) Begin() (err error) {
 	log.Info("transaction begin")
-	if s.tx, err = s.db.Begin(); err != nil {
+	if s.tx, err = s.db.BeginTx(s.Context(), nil); err != nil {
+		err = s.wrapErr(err)
 		log.Error(err)
 		return
 	}
@@ -14,6 +15,7 @@ func (s *Session) Begin() (err error) {
 func (s *Session) Commit() (err error) {
 	log.Info("transaction commit")
 	if err = s.tx.Commit(); err != nil {
+		err = s.wrapErr(err)
 		log.Error(err)
 	}
 	return
@@ -22,6 +24,7 @@ func (s *Session) Commit() (err error) {
 func (s *Session) Rollback() (err error) {
 	log.Info("transaction rollback")
 	if err = s.tx.Rollback(); err != nil {
+		err = s.wrapErr(err)
 		log.Error(err)
 	}
 	return
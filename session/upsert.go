@@ -0,0 +1,141 @@
+package session
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/catbugdemo/sorm/clause"
+	"github.com/catbugdemo/sorm/dialect"
+)
+
+// Conflict sets how the next Insert handles the rows conflicting with
+// existing ones, see OnConflict
+type Conflict struct {
+	session   *Session
+	columns   []string
+	doNothing bool
+	updates   []string
+	updateAll bool
+}
+
+// OnConflict handles the conflicts of the next Insert on the unique columns,
+// empty for the primary key when updating and for any unique constraint
+// otherwise. mysql detects conflicts on every unique key whatever the columns.
+//
+//	s.OnConflict("email").DoUpdate("name").Insert(&users)
+func (s *Session) OnConflict(columns ...string) *Conflict {
+	return &Conflict{session: s, columns: columns}
+}
+
+// DoNothing keeps the conflicting rows as they are. The rows skipped are
+// not returned, they are left as given unless matched on the conflict columns.
+func (c *Conflict) DoNothing() *Session {
+	c.doNothing = true
+	return c.set()
+}
+
+// DoUpdate sets columns of the conflicting rows to the inserted values
+func (c *Conflict) DoUpdate(columns ...string) *Session {
+	c.updates = columns
+	return c.set()
+}
+
+// UpdateAll sets every inserted column of the conflicting rows but the
+// conflict columns and the primary key
+func (c *Conflict) UpdateAll() *Session {
+	c.updateAll = true
+	return c.set()
+}
+
+func (c *Conflict) set() *Session {
+	c.session.conflict = c
+	return c.session
+}
+
+// upsert resolves the conflict handling of an insert of columns, primary
+// are the columns of the primary key
+func (c *Conflict) upsert(primary, columns []string) *dialect.Upsert {
+	upsert := &dialect.Upsert{Columns: c.columns, DoNothing: c.doNothing, Updates: c.updates, Inserted: columns}
+	if !c.doNothing && len(upsert.Columns) == 0 {
+		upsert.Columns = primary
+	}
+	if c.updateAll {
+		upsert.Updates = nil
+		for _, column := range columns {
+			if !contains(primary, column) && !contains(upsert.Columns, column) {
+				upsert.Updates = append(upsert.Updates, column)
+			}
+		}
+	}
+	return upsert
+}
+
+// setConflict sets the ONCONFLICT clause of an insert of columns
+func (s *Session) setConflict(columns []string) {
+	if s.conflict == nil {
+		return
+	}
+	upsert := s.conflict.upsert(primaryColumns(s.RefTable()), columns)
+	s.clause.Set(clause.ONCONFLICT, s.dialect.UpsertSQL(upsert))
+}
+
+// setReturning copies the rows returned by an insert to the inserted ones,
+// in order unless a conflict skipped rows, then matched on the conflict
+// columns or the primary key
+func (s *Session) setReturning(destSlice reflect.Value, returned []reflect.Value, conflict *Conflict) {
+	if len(returned) == destSlice.Len() {
+		for i, dest := range returned {
+			destSlice.Index(i).Set(dest)
+		}
+		return
+	}
+	if conflict == nil {
+		return
+	}
+	columns := conflict.columns
+	if len(columns) == 0 {
+		columns = primaryColumns(s.RefTable())
+	}
+	keys := make(map[string]reflect.Value, len(returned))
+	for _, dest := range returned {
+		if key, ok := s.conflictKey(dest, columns); ok {
+			keys[key] = dest
+		}
+	}
+	for i := 0; i < destSlice.Len(); i++ {
+		key, ok := s.conflictKey(destSlice.Index(i), columns)
+		if dest, found := keys[key]; ok && found {
+			destSlice.Index(i).Set(dest)
+		}
+	}
+}
+
+// conflictKey renders the values of the conflict columns of a row
+func (s *Session) conflictKey(row reflect.Value, columns []string) (string, bool) {
+	if len(columns) == 0 {
+		return "", false
+	}
+	values := make([]string, 0, len(columns))
+	for _, column := range columns {
+		field := s.RefTable().GetFieldBySqlName(column)
+		if field == nil {
+			return "", false
+		}
+		value := field.ValueOf(row)
+		if !value.IsValid() {
+			return "", false
+		}
+		values = append(values, fmt.Sprintf("%v", value.Interface()))
+	}
+	return strings.Join(values, "\x00"), true
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,10 +1,12 @@
 package sorm
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/catbugdemo/sorm/dialect"
 	"github.com/catbugdemo/sorm/log"
+	"github.com/catbugdemo/sorm/schema"
 	"github.com/catbugdemo/sorm/session"
 	"reflect"
 )
@@ -12,6 +14,7 @@ import (
 type Engine struct {
 	db      *sql.DB
 	dialect dialect.Dialect
+	namer   schema.Namer
 }
 
 func Open(driver, source string) (*session.Session, error) {
@@ -39,7 +42,7 @@ func NewEngine(driver, source string) (e *Engine, err error) {
 		log.Errorf("dialect %s Not Found", driver)
 		return
 	}
-	e = &Engine{db: db, dialect: dial}
+	e = &Engine{db: db, dialect: dial, namer: schema.DefaultNamingStrategy}
 	log.Info("Connect database success")
 	return
 }
@@ -53,7 +56,13 @@ func (engine *Engine) Close() {
 }
 
 func (engine *Engine) NewSession() *session.Session {
-	return session.New(engine.db, engine.dialect)
+	return session.New(engine.db, engine.dialect).SetNamingStrategy(engine.namer)
+}
+
+// SetNamingStrategy sets the Namer of the sessions created afterwards,
+// schema.NamingStrategy supports table prefixes, plural tables and initialisms
+func (engine *Engine) SetNamingStrategy(namer schema.Namer) {
+	engine.namer = namer
 }
 
 func ReplaceSqlx(values interface{}) (*session.Session, error) {
@@ -76,7 +85,13 @@ func ReplaceSqlx(values interface{}) (*session.Session, error) {
 type TxFunc func(*session.Session) (interface{}, error)
 
 func (engine *Engine) Transaction(f TxFunc) (result interface{}, err error) {
-	s := engine.NewSession()
+	return engine.TransactionContext(context.Background(), f)
+}
+
+// TransactionContext runs f inside a transaction bound to ctx,
+// the session passed to f carries ctx for every statement it executes
+func (engine *Engine) TransactionContext(ctx context.Context, f TxFunc) (result interface{}, err error) {
+	s := engine.NewSession().WithContext(ctx)
 	if err = s.Begin(); err != nil {
 		return nil, err
 	}
@@ -1,11 +1,15 @@
-package main
+package sorm
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/catbugdemo/sorm/session"
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 	"log"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -151,3 +155,37 @@ func TestHasTable(t *testing.T) {
 	/*	d := gorm.DB{}
 		d.Create()*/
 }
+
+func TestTransactionContext(t *testing.T) {
+	engine, err := NewEngine("sqlite3", filepath.Join(t.TempDir(), "sorm.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	_, err = engine.NewSession().Raw("CREATE TABLE tx_user (id integer PRIMARY KEY, name text)").Exec()
+	assert.Nil(t, err)
+
+	_, err = engine.TransactionContext(context.Background(), func(s *session.Session) (interface{}, error) {
+		return s.Raw("INSERT INTO tx_user(name) VALUES (?)", "a").Exec()
+	})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = engine.TransactionContext(ctx, func(s *session.Session) (interface{}, error) {
+		if _, err := s.Raw("INSERT INTO tx_user(name) VALUES (?)", "b").Exec(); err != nil {
+			return nil, err
+		}
+		cancel()
+		return s.Raw("INSERT INTO tx_user(name) VALUES (?)", "c").Exec()
+	})
+	assert.True(t, errors.Is(err, ErrQueryCanceled))
+
+	_, err = engine.TransactionContext(ctx, func(s *session.Session) (interface{}, error) {
+		return nil, nil
+	})
+	assert.True(t, errors.Is(err, ErrQueryCanceled))
+
+	var names []string
+	assert.Nil(t, engine.NewSession().Raw("SELECT name FROM tx_user").Scan(&names))
+	assert.Equal(t, []string{"a"}, names)
+}